@@ -12,6 +12,8 @@ var ExitedCode2JudgerError = map[int64]JudgerError{
 	126: ENV,
 	137: DELETE, // SIGKILL
 	143: TLE,    // SIGTERM terminated by timeout
+	153: OLE,    // SIGXFSZ 写入的文件超出RLIMIT_FSIZE
+	159: RF,     // SIGSYS 被seccomp杀死
 }
 
 type JudgerError int
@@ -26,8 +28,44 @@ const (
 	OutputNotFound
 	AnswerNotFound
 	UNKNOWN
+	WA  // wrong answer
+	MLE // memory limit exceeded
+	OLE // output limit exceeded
+	SKIPPED
+	PE // presentation error
+	PC // partially correct
+	RF // restricted function, 调用了被禁止的系统调用
 )
 
+// 用例通过
+const AC = NOTHING
+
+var judgerErrorNames = map[JudgerError]string{
+	NOTHING:        "AC",
+	CE:             "CE",
+	RE:             "RE",
+	TLE:            "TLE",
+	ENV:            "ENV",
+	DELETE:         "DELETE",
+	OutputNotFound: "OutputNotFound",
+	AnswerNotFound: "AnswerNotFound",
+	UNKNOWN:        "UNKNOWN",
+	WA:             "WA",
+	MLE:            "MLE",
+	OLE:            "OLE",
+	SKIPPED:        "SKIPPED",
+	PE:             "PE",
+	PC:             "PC",
+	RF:             "RF",
+}
+
+func (e JudgerError) String() string {
+	if name, ok := judgerErrorNames[e]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
+
 type Err struct {
 	Code JudgerError
 	Msg  string
@@ -52,3 +90,15 @@ func IsError(err error, judgerError JudgerError) bool {
 	}
 	return e.Code == judgerError
 }
+
+// 获取err对应的JudgerError，err为nil时返回AC，非Err类型的错误返回UNKNOWN
+func Code(err error) JudgerError {
+	if err == nil {
+		return AC
+	}
+	e, ok := err.(Err)
+	if !ok {
+		return UNKNOWN
+	}
+	return e.Code
+}
@@ -0,0 +1,47 @@
+package docker_executor
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"strings"
+	"tgoj/judger/verifier"
+)
+
+const (
+	checkerTimeout = 10        // checker的时间限制，单位秒
+	checkerMemory  = 256 << 20 // checker的内存限制
+)
+
+var _ verifier.Sandbox = (*DockerExecutor)(nil)
+
+// 在运行容器中执行checker，checker、输入、输出、答案都以只读方式挂载
+// checker 为相对checker目录的路径，其余为宿主机上的路径
+func (d *DockerExecutor) RunChecker(checker, inputFileName, outputFileName, answerFileName string) (int, string, error) {
+	res, err := d.runContainer(&container.Config{
+		Cmd: []string{"sh", "-c",
+			fmt.Sprintf("timeout %v /checker /data/input /data/output /data/answer", checkerTimeout)},
+		Image:           d.runnerContainerImage,
+		NetworkDisabled: true,
+	}, &container.HostConfig{
+		Binds: []string{
+			fmt.Sprintf("%s/checker/%s:/checker:ro", ResourcePath, checker),
+			fmt.Sprintf("%s:/data/input:ro", inputFileName),
+			fmt.Sprintf("%s:/data/output:ro", outputFileName),
+			fmt.Sprintf("%s:/data/answer:ro", answerFileName),
+		},
+		Resources: container.Resources{
+			Memory:     checkerMemory,
+			MemorySwap: checkerMemory,
+		},
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	// testlib 把信息输出到stderr
+	msg := res.Stderr
+	if strings.TrimSpace(msg) == "" {
+		msg = res.Stdout
+	}
+	return int(res.StatusCode), msg, nil
+}
@@ -0,0 +1,114 @@
+package docker_executor
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"log"
+	"tgoj/judger/errors"
+	"tgoj/judger/utils"
+	"time"
+)
+
+// 一次性容器的运行结果
+type containerResult struct {
+	StatusCode int64
+	Stdout     string // 只保留开头和结尾
+	Stderr     string // 只保留开头和结尾
+	WallTime   time.Duration
+	OOMKilled  bool // 容器的主进程是否被OOM killer杀死
+	Killed     bool // 容器结束前ctx已结束，容器被提前杀死
+}
+
+// 创建并启动一个容器，等待容器结束后删除容器
+// 容器结束时docker返回错误信息则视为ENV错误
+func (d *DockerExecutor) runContainer(config *container.Config, hostConfig *container.HostConfig) (containerResult, error) {
+	return d.runContainerStream(context.Background(), config, hostConfig, nil)
+}
+
+// 与runContainer相同，stdout不为nil时容器的stdout写入stdout，不再记录在结果中
+// ctx 结束时立即杀死容器，用于流式校验时提前结束程序
+func (d *DockerExecutor) runContainerStream(ctx context.Context, config *container.Config, hostConfig *container.HostConfig,
+	stdout io.Writer) (res containerResult, err error) {
+	config.AttachStdout = true
+	config.AttachStderr = true
+	resp, err := d.cli.ContainerCreate(context.Background(), config, hostConfig, nil, nil, "")
+	if err != nil {
+		return
+	}
+	// 不使用AutoRemove，以便在容器结束后检查是否被OOM killer杀死
+	defer d.removeContainer(resp.ID)
+
+	hijackedResponse, err := d.cli.ContainerAttach(context.Background(), resp.ID, types.ContainerAttachOptions{
+		Stream: true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return
+	}
+	defer hijackedResponse.Close()
+
+	// 未使用Tty，stdout和stderr是多路复用的
+	// 在容器运行的同时读取，避免输出过多时容器阻塞在写stdout、stderr上
+	stdoutBuf, stderr := utils.NewHeadTailBuffer(stderrLimit, stderrLimit), utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	if stdout == nil {
+		stdout = stdoutBuf
+	}
+	copyErrCh := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, hijackedResponse.Reader)
+		copyErrCh <- err
+	}()
+
+	start := time.Now()
+	if err = d.cli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{}); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := d.cli.ContainerKill(context.Background(), resp.ID, "KILL"); err != nil {
+				log.Println(resp.ID, err)
+			}
+		case <-done:
+		}
+	}()
+
+	status, err := d.exec(resp.ID)
+	res.WallTime = time.Since(start)
+	res.Killed = ctx.Err() != nil
+	if err != nil {
+		return
+	}
+	if status.Error != nil && len(status.Error.Message) > 0 {
+		return res, errors.New(errors.ENV, status.Error.Message)
+	}
+	res.StatusCode = status.StatusCode
+
+	if err = <-copyErrCh; err != nil {
+		return
+	}
+	res.Stdout, res.Stderr = stdoutBuf.String(), stderr.String()
+
+	inspect, err := d.cli.ContainerInspect(context.Background(), resp.ID)
+	if err != nil {
+		log.Println(resp.ID, err)
+		return res, nil
+	}
+	res.OOMKilled = inspect.State != nil && inspect.State.OOMKilled
+	return res, nil
+}
+
+func (d *DockerExecutor) removeContainer(id string) {
+	if err := d.cli.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{
+		Force: true,
+	}); err != nil {
+		log.Println(id, err)
+	}
+}
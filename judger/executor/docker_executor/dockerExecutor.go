@@ -11,20 +11,20 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
 	"tgoj/judger"
 	"tgoj/judger/errors"
 	"tgoj/judger/executor"
+	"tgoj/judger/language"
 	"tgoj/judger/utils"
-	"tgoj/judger/verifier"
 )
 
 const (
 	DefaultCompileContainerName = "golang:1.15"
 	DefaultRunnerContainerName  = "alpine:latest"
 	//DEBUG = true
-	DefaultChannelSize = 100
+	syntaxCheckTimeout = 10      // 语法检查的时间限制，单位秒
+	stderrLimit        = 4 << 10 // 保留的stderr大小
 )
 
 var ResourcePath string
@@ -34,87 +34,23 @@ func init() {
 	ResourcePath = os.Getenv("Resource")
 }
 
-type Status int
-
-const (
-	CREATED Status = iota
-	RUNNING
-	DESTROYING // 等待所有任务结束
-	DESTROYED  // 已销毁，不能使用
-)
-
 var _ executor.Executor = (*DockerExecutor)(nil)
 
+var _ executor.Backend = (*DockerExecutor)(nil)
+
+// 编译、运行都在docker容器中进行，阶段调度和校验由 executor.Pipeline 完成
 type DockerExecutor struct {
 	sync.Mutex
-	ctx        context.Context
-	cancelFunc context.CancelFunc
-
-	resultCh chan<- judger.Result
-	taskCh   <-chan *judger.Task
-
-	compileTaskCh compileTaskChan
-	runTaskCh     runTaskChan
-	verifyTaskCh  verifyTaskChan
-
-	cli                    *client.Client // docker client
-	compilerContainerImage string
-	compilerContainerID    string
-	runnerContainerImage   string
-	enableCompile          bool
-	verifier               verifier.Verifier
-	status                 Status
-}
-
-/****  Initialization      *****/
-func (d *DockerExecutor) SetVerifier(v verifier.Verifier) error {
-	d.verifier = v
-	return nil
-}
-
-// 如果设置了n>0 且 没有启动编译容器，会自动启动编译容器
-func (d *DockerExecutor) SetCompileConcurrency(n int) error {
-	if n <= 0 {
-		return fmt.Errorf("if set, compile concurrency must be greater than 0, but received %v", n)
-	}
-
-	if d.compilerContainerID == "" {
-		if err := d.startCompiler(); err != nil {
-			return err
-		}
-	}
+	*executor.Pipeline
 
-	for i := 0; i < n; i++ {
-		d.compileTaskCh.Add(1)
-		go d.Compile()
-	}
-	return nil
-}
-
-func (d *DockerExecutor) SetRunConcurrency(n int) error {
-	if n <= 0 {
-		return fmt.Errorf("if set, run concurrency must be greater than 0, but received %v", n)
-	}
-
-	for i := 0; i < n; i++ {
-		d.runTaskCh.Add(1)
-		go d.Run()
-	}
-	return nil
-}
-
-func (d *DockerExecutor) SetVerifyConcurrency(n int) error {
-	if n <= 0 {
-		return fmt.Errorf("if set, verify concurrency must be greater than 0, but received %v", n)
-	}
-
-	for i := 0; i < n; i++ {
-		d.verifyTaskCh.Add(1)
-		go d.Verify()
-	}
-	return nil
+	cli                    *client.Client    // docker client
+	compilerContainerImage string            // 未指定编译镜像的语言使用该镜像
+	compilers              map[string]string // 编译镜像 -> 编译容器ID，每种镜像只启动一个编译容器
+	runnerContainerImage   string            // 未指定运行镜像的语言使用该镜像
+	pool                   *runnerPool       // 运行容器池，为nil时每个用例使用一次性的容器
 }
 
+/****  Initialization      *****/
 func (d *DockerExecutor) SetCompilerContainer(image string) error {
 	d.compilerContainerImage = image
 	return nil
@@ -125,21 +61,10 @@ func (d *DockerExecutor) SetRunnerContainer(image string) error {
 	return nil
 }
 
-func (d *DockerExecutor) SetResultChan(resultCh chan<- judger.Result) error {
-	d.resultCh = resultCh
-	return nil
-}
-
-func (d *DockerExecutor) SetTaskChan(taskCh <-chan *judger.Task) error {
-	d.taskCh = taskCh
-	return nil
-}
-
+// 启动默认编译镜像的编译容器，其他镜像的编译容器在第一次编译时启动
 func (d *DockerExecutor) EnableCompiler() error {
-	if d.compilerContainerID == "" {
-		return d.startCompiler()
-	}
-	return nil
+	_, err := d.compiler(d.compilerContainerImage)
+	return err
 }
 
 func New(opts ...executor.Option) *DockerExecutor {
@@ -148,19 +73,13 @@ func New(opts ...executor.Option) *DockerExecutor {
 		panic(err)
 	}
 
-	ctx, cancelFunc := context.WithCancel(context.Background())
 	d := &DockerExecutor{
-		ctx:                    ctx,
-		cancelFunc:             cancelFunc,
 		cli:                    cli,
 		compilerContainerImage: DefaultCompileContainerName,
+		compilers:              map[string]string{},
 		runnerContainerImage:   DefaultRunnerContainerName,
-		runTaskCh:              newRunTaskChan(DefaultChannelSize),
-		compileTaskCh:          newCompileTaskChan(DefaultChannelSize),
-		verifyTaskCh:           newVerifyTaskChan(DefaultChannelSize),
-		verifier:               verifier.StandardVerifier{},
-		status:                 CREATED,
 	}
+	d.Pipeline = executor.NewPipeline(d)
 
 	for _, opt := range opts {
 		if err = opt(d); err != nil {
@@ -172,28 +91,16 @@ func New(opts ...executor.Option) *DockerExecutor {
 
 /****  Operation      *****/
 func (d *DockerExecutor) Destroy(force bool) error {
-	// 停止所有 goroutine
-	// 如果在RUNNING状态收到退出的信息，说明是强制退出，不会处理内部还有的任务
-	// 如果在DESTROYING状态收到退出的信息，则是非强制退出，可以依次等待每个阶段残留的任务运行完成后再退出
-	//     每个阶段处理完task后，关闭发往下一个阶段的channel
-	if !force {
-		d.status = DESTROYING
+	// 等待各阶段的goroutine结束后再删除容器
+	if err := d.Pipeline.Destroy(force); err != nil {
+		return err
 	}
-	d.cancelFunc()
-
-	d.compileTaskCh.Wait()
-	close(d.runTaskCh.ch)
-
-	d.runTaskCh.Wait()
-	close(d.verifyTaskCh.ch)
-
-	d.verifyTaskCh.Wait()
-	d.status = DESTROYED
+	d.closeRunnerPool()
 
 	// 删除容器
 	log.Println("remove compile container")
-	if d.compilerContainerID != "" {
-		if err := d.cli.ContainerRemove(context.Background(), d.compilerContainerID, types.ContainerRemoveOptions{
+	for _, id := range d.compilers {
+		if err := d.cli.ContainerRemove(context.Background(), id, types.ContainerRemoveOptions{
 			Force: true,
 		}); err != nil {
 			log.Println(err)
@@ -203,109 +110,33 @@ func (d *DockerExecutor) Destroy(force bool) error {
 	return nil
 }
 
-func (d *DockerExecutor) Execute() error {
-	d.status = RUNNING
-	for {
-		select {
-		case <-d.ctx.Done():
-			// compileTaskCh 只有一个sender，所以可以直接关闭
-			close(d.compileTaskCh.ch)
-			return nil
-		case task := <-d.taskCh: // 接收外部传入的任务，并根据任务状态执行
-			//log.Println("execute task: ", task.ID)
-			switch task.Status {
-			case judger.CREATED:
-				d.compileTaskCh.ch <- compileTask{
-					Task: task,
-				}
-			case judger.COMPILED:
-				inputDir, inputFile := filepath.Split(task.InputPath)
-				outputDir, outputFile := filepath.Split(task.OutputPath)
-				d.runTaskCh.ch <- runTask{
-					Task:           task,
-					InputDirName:   inputDir,
-					InputFileName:  inputFile,
-					OutputDirName:  outputDir,
-					OutputFileName: outputFile,
-				}
-			case judger.EXECUTED:
-				d.verifyTaskCh.ch <- verifyTask{Task: task}
-			}
-		}
-	}
-}
-
-func (d *DockerExecutor) Compile() {
-	defer func() {
-		d.compileTaskCh.Done()
-	}()
-
-	var task compileTask
-	var ok bool
-	for {
-		select {
-		case <-d.ctx.Done():
-			d.finishCompile()
-			return
-		case task, ok = <-d.compileTaskCh.ch:
-			if !ok {
-				d.finishCompile()
-				return
-			}
-			//log.Println("compile task: ", task.ID)
-			d.processCompileTask(task)
-		}
+// 在该语言的编译容器中编译，编译容器出错时重启后再编译一次
+func (d *DockerExecutor) CompileTask(task *judger.Task, lang *language.Language) error {
+	image := d.compilerImage(lang)
+	err := d.compileCode(task, lang, image)
+	if rerun, e := d.checkCompilerError(image, err); rerun {
+		err = d.compileCode(task, lang, image)
+	} else {
+		err = e
 	}
+	return err
 }
 
-func (d *DockerExecutor) finishCompile() {
-	if d.status == DESTROYING { // 非强制退出
-		log.Println("processing left compile task")
-		// compileTaskCh 已关闭，因为带缓冲，处理完channel内剩余task再退出
-		for task := range d.compileTaskCh.ch {
-			d.processCompileTask(task)
-		}
+// 该语言使用的编译容器镜像
+func (d *DockerExecutor) compilerImage(lang *language.Language) string {
+	if lang.CompilerImage == "" {
+		return d.compilerContainerImage
 	}
+	return lang.CompilerImage
 }
 
-func (d *DockerExecutor) processCompileTask(task compileTask) {
-	// 可执行文件相对exe目录的路径 与 源代码文件相对code目录的路径 相同
-	task.ExePath = strings.TrimSuffix(task.CodePath, ".go")
-	err, rerun := d.compile(task)
-
+// 在编译容器中把 CodePath 编译为 ExePath，编译失败返回CE
+func (d *DockerExecutor) compileCode(task *judger.Task, lang *language.Language, image string) (err error) {
+	compilerID, err := d.compiler(image)
 	if err != nil {
-		d.resultCh <- judger.Result{
-			ID:      task.ID,
-			Success: false,
-			Error:   err,
-		}
 		return
 	}
 
-	if rerun {
-		return
-	}
-
-	inputDir, inputFile := filepath.Split(task.InputPath)
-	outputDir, outputFile := filepath.Split(task.OutputPath)
-	d.runTaskCh.ch <- runTask{
-		Task:           task.Task,
-		InputDirName:   inputDir,
-		InputFileName:  inputFile,
-		OutputDirName:  outputDir,
-		OutputFileName: outputFile,
-	}
-}
-
-func (d *DockerExecutor) compile(task compileTask) (err error, rerun bool) {
-	defer func() {
-		if rerun, err = d.checkCompilerError(err); err != nil {
-			return
-		} else if rerun {
-			d.compileTaskCh.ch <- task
-		}
-	}()
-
 	input, output := task.CodePath, task.ExePath
 	// 保证目录存在
 	outputDir := filepath.Dir(output)
@@ -313,10 +144,10 @@ func (d *DockerExecutor) compile(task compileTask) (err error, rerun bool) {
 		utils.CheckDirectoryExist(fmt.Sprintf("%s/exe/%s", ResourcePath, outputDir))
 	}
 
-	resp, err := d.cli.ContainerExecCreate(context.Background(), d.compilerContainerID, types.ExecConfig{
-		// disable optimize and inline   -gcflags '-N -l'
+	resp, err := d.cli.ContainerExecCreate(context.Background(), compilerID, types.ExecConfig{
+		// go build -o /exe/1/success /code/1/success.go
 		Cmd: []string{"sh", "-c",
-			fmt.Sprintf("go build -o /exe/%s /code/%s", output, input)},
+			lang.CompileCommand(fmt.Sprintf("/code/%s", input), fmt.Sprintf("/exe/%s", output))},
 		AttachStderr: true,
 		AttachStdout: true,
 	})
@@ -345,182 +176,137 @@ func (d *DockerExecutor) compile(task compileTask) (err error, rerun bool) {
 		return
 	}
 	if inspect.ExitCode != 0 {
-		return errors.New(errors.CE, commandOutput), false
+		return errors.New(errors.CE, commandOutput)
 	}
 
 	return
 }
 
-func (d *DockerExecutor) Run() {
-	defer func() {
-		d.runTaskCh.Done()
-	}()
-
-	var task runTask
-	var ok bool
-	for {
-		select {
-		case <-d.ctx.Done():
-			d.finishRun()
-			return
-		case task, ok = <-d.runTaskCh.ch:
-			if !ok {
-				d.finishRun()
-				return
-			}
-			//log.Println("run task: ", task.ID)
-			d.processRunTask(task)
-		}
+// 运行第i个用例，运行时间等信息记录在caseRes中
+func (d *DockerExecutor) RunCase(task executor.RunTask, i int, caseRes *judger.CaseResult) error {
+	inputDir, inputFile := filepath.Split(task.TestCases[i].InputPath)
+	outputDir, outputFile := filepath.Split(task.TestCases[i].OutputPath)
+
+	// 保证目录存在
+	if outputDir != "" {
+		utils.CheckDirectoryExist(fmt.Sprintf("%s/output/%s", ResourcePath, outputDir))
 	}
-}
 
-func (d *DockerExecutor) finishRun() {
-	if d.status == DESTROYING { // 非强制退出
-		log.Println("processing left run task")
-		// runTaskCh 已关闭，因为带缓冲，处理完channel内剩余task再退出
-		for task := range d.runTaskCh.ch {
-			d.processRunTask(task)
-		}
+	outputPath := fmt.Sprintf("%s/output/%s", ResourcePath, task.TestCases[i].OutputPath)
+	if d.pool != nil {
+		return d.runPooled(task, i, outputPath, caseRes)
+	}
+	if err := createOutputFile(outputPath); err != nil {
+		return err
 	}
-}
 
-func (d *DockerExecutor) processRunTask(task runTask) {
-	err := d.run(task)
-	//log.Println("run task finish: ", task.ID, err)
+	res, err := d.runContainer(
+		// set -o pipefail; echo $(tr "\n" " " < /input/1.go) | timeout 2.5 /exe | head -c 16777217 > /output/1.txt
+		d.runnerConfig(task, withStat(fmt.Sprintf("%s > /output/%s", runPipeline(task, "/input/"+inputFile, "/exe"), outputFile))),
+		runnerHostConfig(task,
+			fmt.Sprintf("%s:/exe:ro", exeHostPath(task.Task, task.Lang)),
+			fmt.Sprintf("%s:/output/%s", outputPath, outputFile),
+			fmt.Sprintf("%s/input/%s:/input:ro", ResourcePath, inputDir),
+		))
+	caseRes.WallTime = res.WallTime
 	if err != nil {
-		d.resultCh <- judger.Result{
-			ID:      task.ID,
-			Success: false,
-			Error:   err,
-		}
-		return
+		log.Println(task.ID, err)
+		return err
 	}
 
-	task.Task.Status = judger.EXECUTED
-	d.verifyTaskCh.ch <- verifyTask{Task: task.Task}
+	// stdout已重定向到输出文件，统计信息在stderr的最后一行
+	msg, events := parseStat(res.Stderr, caseRes)
+	return outputError(task, outputPath, res, events, msg)
 }
 
-func (d *DockerExecutor) run(task runTask) error {
-	// 保证目录存在
-	if task.OutputDirName != "." {
-		utils.CheckDirectoryExist(fmt.Sprintf("%s/output/%s", ResourcePath, task.OutputDirName))
+// 根据输出文件的大小 和 运行结果得到运行错误
+func outputError(task executor.RunTask, outputPath string, res containerResult, events memoryEvents, msg string) error {
+	// 超出输出限制时，head退出导致程序收到SIGPIPE，因此先于退出码判断
+	outputLimit := task.MaxOutput()
+	if info, statErr := os.Stat(outputPath); statErr == nil && info.Size() > outputLimit {
+		return errors.New(errors.OLE, fmt.Sprintf("output exceeds %v bytes", outputLimit))
 	}
 
-	resp, err := d.cli.ContainerCreate(context.Background(), &container.Config{
-		// echo $(tr "\n" " " < /input/1.go) | timeout 2.5 /exe > /output/1.txt
-		Cmd: []string{"sh", "-c",
-			fmt.Sprintf("echo $(tr \"\\n\" \" \" < /input/%s) | timeout %v /exe > /output/%s",
-				task.InputFileName, strconv.FormatFloat(task.Timeout, 'f', 4, 32), task.OutputFileName),
-		},
-		//Cmd: []string{"sh", "-c", "while true; do sleep 100; done"}, // for debug
-		Image:        d.runnerContainerImage,
-		AttachStdout: true,
-		AttachStderr: true,
-	}, &container.HostConfig{
-		Binds: []string{
-			fmt.Sprintf("%s/exe/%s:/exe:ro", ResourcePath, task.ExePath),
-			fmt.Sprintf("%s/output/%s:/output", ResourcePath, task.OutputDirName),
-			fmt.Sprintf("%s/input/%s:/input:ro", ResourcePath, task.InputDirName),
-		},
-		AutoRemove: true,
-		Resources: container.Resources{
-			Memory:     task.Memory,
-			MemorySwap: task.Memory,
-			CPUPeriod:  task.CpuPeriod,
-			CPUQuota:   task.CpuQuota,
-		},
-	}, nil, nil, "")
-	if err != nil {
-		log.Println(task.ID, err)
-		return err
+	return runError(res, events, msg)
+}
+
+// 运行用例的命令，input、exe为容器内的路径，通过head限制输出的大小，多输出1个字节用于判断是否超出限制
+func runPipeline(task executor.RunTask, input, exe string) string {
+	return fmt.Sprintf("set -o pipefail; echo $(tr \"\\n\" \" \" < %s) | timeout %v %s | head -c %d",
+		input, strconv.FormatFloat(task.Lang.Timeout(task.Timeout), 'f', 4, 32), task.Lang.RunCommand(exe),
+		task.MaxOutput()+1)
+}
+
+// 程序被SIGKILL杀死时shell的退出码，128+9
+const sigkillExitCode = 137
+
+// 根据运行容器的退出码、内存事件得到运行错误，msg为程序的stderr
+func runError(res containerResult, events memoryEvents, msg string) error {
+	if res.StatusCode == 0 {
+		return nil
 	}
 
-	hijackedResponse, err := d.cli.ContainerAttach(context.Background(), resp.ID, types.ContainerAttachOptions{
-		Stream: true,
-		Stdout: true,
-		Stderr: true,
-	})
-	if err != nil {
-		log.Println(task.ID, err)
+	// 被seccomp杀死(SIGSYS)时不再判断内存
+	v, ok := errors.ExitedCode2JudgerError[res.StatusCode]
+	if v == errors.RF {
+		return errors.New(errors.RF, msg)
+	}
+	if memoryLimitExceeded(res, events) {
+		return errors.New(errors.MLE, msg)
 	}
+	if ok {
+		return errors.New(v, msg)
+	}
+	return errors.New(errors.UNKNOWN, msg)
+}
 
-	if err = d.cli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{}); err != nil {
-		log.Println(task.ID, err)
-		return err
+// 解释型语言在运行用例前检查语法，失败视为CE
+func (d *DockerExecutor) SyntaxCheck(task executor.RunTask) error {
+	if task.Lang.CheckCmd == "" {
+		return nil
 	}
 
-	status, err := d.exec(resp.ID)
+	res, err := d.runContainer(
+		d.runnerConfig(task, fmt.Sprintf("timeout %v %s", syntaxCheckTimeout, task.Lang.CheckCommand("/exe"))),
+		runnerHostConfig(task, fmt.Sprintf("%s:/exe:ro", exeHostPath(task.Task, task.Lang))))
 	if err != nil {
 		log.Println(task.ID, err)
 		return err
 	}
-
-	if status.Error != nil && len(status.Error.Message) > 0 {
-		err = errors.New(errors.ENV, status.Error.Message)
-	} else {
-		var msg string
-		msg, err = utils.ReadFromBIO(hijackedResponse.Reader)
-		if err != nil {
-			return err
-		}
-
-		if status.StatusCode == 0 {
-			return nil
-		}
-
-		if v, ok := errors.ExitedCode2JudgerError[status.StatusCode]; ok {
-			err = errors.New(v, msg)
-		} else {
-			err = errors.New(errors.UNKNOWN, msg)
-		}
+	if res.StatusCode != 0 {
+		return errors.New(errors.CE, res.Stdout+res.Stderr)
 	}
-	//inspect, err := d.cli.ContainerInspect(context.Background(), resp.ID)
-	//log.Println(inspect)
-	return err
+	return nil
 }
 
-func (d *DockerExecutor) Verify() {
-	defer func() {
-		d.verifyTaskCh.Done()
-	}()
-
-	var task verifyTask
-	var ok bool
-	for {
-		select {
-		case <-d.ctx.Done():
-			d.finishVerify()
-			return
-		case task, ok = <-d.verifyTaskCh.ch:
-			if !ok {
-				d.finishVerify()
-				return
-			}
-			//log.Println("verify task: ", task.ID)
-			d.processVerifyTask(task)
-		}
+// 程序异常退出后，判断是否因为超出内存限制
+// 容器的主进程被OOM killer杀死时 State.OOMKilled 为true；sh 的子进程被杀死时只能从cgroup的内存事件中得知
+// 内存使用达到过限制只作为辅助判断: 仅在程序被SIGKILL杀死(退出码137)时视为MLE，其他异常退出按退出码判定
+func memoryLimitExceeded(res containerResult, events memoryEvents) bool {
+	if res.OOMKilled || events.OOMKill > 0 {
+		return true
 	}
+	return events.LimitHit > 0 && res.StatusCode == sigkillExitCode
 }
 
-func (d *DockerExecutor) finishVerify() {
-	if d.status == DESTROYING { // 非强制退出
-		log.Println("processing left verify task")
-		// verifyTaskCh 已关闭，因为带缓冲，处理完channel内剩余task再退出
-		for task := range d.verifyTaskCh.ch {
-			d.processVerifyTask(task)
-		}
+// 该语言使用的运行容器镜像
+func (d *DockerExecutor) runnerImage(lang *language.Language) string {
+	if lang.RunnerImage == "" {
+		return d.runnerContainerImage
 	}
+	return lang.RunnerImage
 }
 
-func (d *DockerExecutor) processVerifyTask(task verifyTask) {
-	_, err := d.verifier.Verify(fmt.Sprintf("%s/output/%s", ResourcePath, task.OutputPath),
-		fmt.Sprintf("%s/answer/%s", ResourcePath, task.AnswerPath))
-
-	d.resultCh <- judger.Result{
-		ID:      task.ID,
-		Success: err == nil,
-		Error:   err,
+// 运行容器中 /exe 在宿主机上的路径，解释型语言直接使用源代码文件
+func exeHostPath(task *judger.Task, lang *language.Language) string {
+	if lang.Interpreted() {
+		return fmt.Sprintf("%s/code/%s", ResourcePath, task.CodePath)
 	}
+	return fmt.Sprintf("%s/exe/%s", ResourcePath, task.ExePath)
+}
+
+func (d *DockerExecutor) Resource() string {
+	return ResourcePath
 }
 
 func (d *DockerExecutor) exec(id string) (container.ContainerWaitOKBody, error) {
@@ -534,12 +320,24 @@ func (d *DockerExecutor) exec(id string) (container.ContainerWaitOKBody, error)
 	}
 }
 
-// 启动一个编译容器 并记录容器ID
-func (d *DockerExecutor) startCompiler() error {
+// 获取镜像对应的编译容器ID，编译容器不存在时启动
+func (d *DockerExecutor) compiler(image string) (string, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	if id, ok := d.compilers[image]; ok {
+		return id, nil
+	}
+	return d.startCompiler(image)
+}
+
+// 启动一个编译容器，启动成功后记录容器ID，调用前需要加锁
+func (d *DockerExecutor) startCompiler(image string) (string, error) {
 	resp, err := d.cli.ContainerCreate(context.Background(), &container.Config{
+		Cmd:       []string{"sh"},
 		Tty:       true,
 		OpenStdin: true,
-		Image:     d.compilerContainerImage,
+		Image:     image,
 	}, &container.HostConfig{
 		Binds: []string{
 			fmt.Sprintf("%s/code:/code", ResourcePath),
@@ -547,33 +345,38 @@ func (d *DockerExecutor) startCompiler() error {
 		},
 	}, nil, nil, "")
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	d.compilerContainerID = resp.ID
-	return d.cli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{})
+	// 启动失败时删除容器，不记录ID，下次使用时重新创建
+	if err = d.cli.ContainerStart(context.Background(), resp.ID, types.ContainerStartOptions{}); err != nil {
+		d.removeContainer(resp.ID)
+		return "", err
+	}
+	d.compilers[image] = resp.ID
+	return resp.ID, nil
 }
 
-func (d *DockerExecutor) restartCompiler() error {
+func (d *DockerExecutor) restartCompiler(image string) error {
 	d.Lock()
 	defer d.Unlock()
 
-	_, err := d.cli.ContainerInspect(context.Background(), d.compilerContainerID)
+	_, err := d.cli.ContainerInspect(context.Background(), d.compilers[image])
 	if errdefs.IsNotFound(err) {
-		return d.startCompiler()
+		_, err = d.startCompiler(image)
 	}
 	return err
 }
 
 // if recover from error by restarting compiler, and restart success, then need to rerun
 // if fail to restart compiler, don't rerun
-func (d *DockerExecutor) checkCompilerError(err error) (rerun bool, e error) {
+func (d *DockerExecutor) checkCompilerError(image string, err error) (rerun bool, e error) {
 	if err == nil || errors.IsError(err, errors.CE) {
 		return false, err
 	}
 
 	log.Println("compiler error: ", err)
-	if err = d.restartCompiler(); err != nil {
+	if err = d.restartCompiler(image); err != nil {
 		return false, err
 	}
 	return true, nil
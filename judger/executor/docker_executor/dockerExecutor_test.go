@@ -8,11 +8,15 @@ import (
 	"io"
 	"log"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 	"tgoj/judger"
+	"tgoj/judger/errors"
 	"tgoj/judger/executor"
+	"tgoj/judger/language"
+	"tgoj/judger/utils"
 	"tgoj/judger/verifier"
 	"time"
 )
@@ -39,19 +43,21 @@ func TestDockerExecutor_Run(t *testing.T) {
 	ch := make(chan struct{})
 
 	go func() {
-		var n = 6
+		var n = 9
 		var tasks []judger.Task
-		for i := 0; i < 7; i++ {
+		for i := 0; i < n; i++ {
 			tasks = append(tasks, judger.Task{
-				ID:         int64(i),
-				AnswerPath: "1.txt",
-				InputPath:  "1.txt",
-				OutputPath: fmt.Sprintf("1//%v.txt", i),
-				CpuPeriod:  100000,
-				CpuQuota:   50000,
-				Timeout:    1.0,
-				Memory:     20 << 20, // 20 MB for WSL, 10MB for linux like ubuntu、centos
-				Status:     judger.CREATED,
+				ID: int64(i),
+				TestCases: []judger.TestCase{
+					{InputPath: "1.txt", AnswerPath: "1.txt", OutputPath: fmt.Sprintf("1//%v_1.txt", i)},
+					{InputPath: "2.txt", AnswerPath: "2.txt", OutputPath: fmt.Sprintf("1//%v_2.txt", i)},
+				},
+				CpuPeriod:   100000,
+				CpuQuota:    50000,
+				Timeout:     1.0,
+				Memory:      20 << 20, // 20 MB for WSL, 10MB for linux like ubuntu、centos
+				OutputLimit: 1 << 20,
+				Status:      judger.CREATED,
 			})
 		}
 
@@ -62,7 +68,9 @@ func TestDockerExecutor_Run(t *testing.T) {
 		tasks[3].CodePath = "ce.go"
 		tasks[4].CodePath = "timeout.go"
 		tasks[5].CodePath = "success.go"
-		tasks[6].CodePath = "rm.go"
+		tasks[6].CodePath = "ole.go"
+		tasks[7].CodePath = "rm.go"
+		tasks[8].CodePath = "socket.go"
 
 		for i := 0; i < n; i++ {
 			log.Println("put task: ", i)
@@ -78,6 +86,10 @@ func TestDockerExecutor_Run(t *testing.T) {
 		for i := 0; i < n; i++ {
 			res := <-resultCh
 			log.Println(res)
+			// socket.go 被seccomp禁止
+			if res.ID == 8 && res.Verdict != errors.RF {
+				t.Errorf("socket.go: verdict %v, want RF", res.Verdict)
+			}
 		}
 
 		//if err := dockerExecutor.Destroy(false); err != nil {
@@ -94,6 +106,108 @@ func TestDockerExecutor_Run(t *testing.T) {
 	<-ch
 }
 
+// 运行容器的隔离设置：非root用户、只读根文件系统、去掉所有capabilities、no-new-privileges、禁用网络、进程数量限制、seccomp
+func TestDockerExecutor_Lockdown(t *testing.T) {
+	taskCh, resultCh := make(chan *judger.Task), make(chan judger.Result, 100)
+	dockerExecutor := New(
+		executor.EnableCompiler(),
+		executor.WithResultChan(resultCh),
+		executor.WithTaskChan(taskCh),
+		executor.WithCompileConcurrency(3),
+		executor.WithRunConcurrency(3),
+		executor.WithVerifyConcurrency(3),
+	)
+	go dockerExecutor.Execute()
+
+	var tests = []struct {
+		codePath string
+		answer   string
+		verdict  errors.JudgerError
+	}{
+		{"lockdown.go", "lockdown.txt", errors.AC}, // 输出uid、只读、capabilities、no_new_privs、网卡
+		{"threads.go", "1.txt", errors.RE},         // 超出进程数量限制
+		{"socket.go", "1.txt", errors.RF},          // 被seccomp禁止
+		{"clone.go", "1.txt", errors.RF},           // 创建命名空间被seccomp禁止
+		{"bypass.go", "1.txt", errors.OLE},         // 直接写输出文件，受fsize限制
+	}
+	for i, test := range tests {
+		taskCh <- &judger.Task{
+			ID:        int64(i),
+			CodePath:  test.codePath,
+			TestCases: []judger.TestCase{{InputPath: "1.txt", AnswerPath: test.answer, OutputPath: fmt.Sprintf("lockdown/%v.txt", i)}},
+			Timeout:   1.0,
+			Memory:    64 << 20,
+			Status:    judger.CREATED,
+		}
+	}
+
+	for range tests {
+		res := <-resultCh
+		log.Println(res)
+		if test := tests[res.ID]; res.Verdict != test.verdict {
+			t.Errorf("%v: verdict %v, want %v", test.codePath, res.Verdict, test.verdict)
+		}
+	}
+	if err := dockerExecutor.Destroy(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 运行容器池: 同一个容器依次运行多个任务，异常判定后容器被删除，结果与一次性容器相同
+func TestDockerExecutor_RunnerPool(t *testing.T) {
+	taskCh, resultCh := make(chan *judger.Task), make(chan judger.Result, 100)
+	dockerExecutor := New(
+		executor.EnableCompiler(),
+		executor.WithResultChan(resultCh),
+		executor.WithTaskChan(taskCh),
+		executor.WithCompileConcurrency(3),
+		executor.WithRunConcurrency(1),
+		executor.WithVerifyConcurrency(3),
+		WithRunnerPool(2, 10),
+	)
+	go dockerExecutor.Execute()
+
+	var tests = []struct {
+		codePath string
+		verdict  errors.JudgerError
+	}{
+		{"success.go", errors.AC},
+		{"success.go", errors.AC},
+		{"out_of_bound.go", errors.RE},
+		{"timeout.go", errors.TLE},
+		{"success.go", errors.AC},
+		// 同一个容器中的下一次运行不能看到上一次写入 /dev/shm 的文件
+		{"shm.go", errors.AC},
+		{"shm.go", errors.AC},
+	}
+	for i, test := range tests {
+		taskCh <- &judger.Task{
+			ID:       int64(i),
+			CodePath: test.codePath,
+			TestCases: []judger.TestCase{
+				{InputPath: "1.txt", AnswerPath: "1.txt", OutputPath: fmt.Sprintf("pool/%v_1.txt", i)},
+				{InputPath: "2.txt", AnswerPath: "2.txt", OutputPath: fmt.Sprintf("pool/%v_2.txt", i)},
+			},
+			CpuPeriod: 100000,
+			CpuQuota:  50000,
+			Timeout:   1.0,
+			Memory:    64 << 20,
+			Status:    judger.CREATED,
+		}
+	}
+
+	for range tests {
+		res := <-resultCh
+		log.Println(res)
+		if test := tests[res.ID]; res.Verdict != test.verdict {
+			t.Errorf("%v: verdict %v, want %v", test.codePath, res.Verdict, test.verdict)
+		}
+	}
+	if err := dockerExecutor.Destroy(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDockerExecutor_Exec(t *testing.T) {
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
@@ -126,22 +240,24 @@ func TestDockerExecutor_PullImage(t *testing.T) {
 
 func TestDockerExecutor_Compile(t *testing.T) {
 	dockerExecutor := New(executor.EnableCompiler())
-	task := compileTask{
-		Task: &judger.Task{
-			ID:         1,
-			AnswerPath: "1.txt",
-			InputPath:  "1.txt",
-			OutputPath: fmt.Sprintf("1//%v.txt", 1),
-			CpuPeriod:  100000,
-			CpuQuota:   50000,
-			Timeout:    1.0,
-			Memory:     8 << 20, // 16 MB
-			Status:     judger.CREATED,
-			CodePath:   "1//success.go",
-			ExePath:    "1//success",
+	task := &judger.Task{
+		ID: 1,
+		TestCases: []judger.TestCase{
+			{InputPath: "1.txt", AnswerPath: "1.txt", OutputPath: fmt.Sprintf("1//%v.txt", 1)},
 		},
+		CpuPeriod: 100000,
+		CpuQuota:  50000,
+		Timeout:   1.0,
+		Memory:    8 << 20, // 16 MB
+		Status:    judger.CREATED,
+		CodePath:  "1//success.go",
+		ExePath:   "1//success",
 	}
-	err, _ := dockerExecutor.compile(task)
+	lang, err := executor.TaskLanguage(task)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = dockerExecutor.CompileTask(task, lang)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -163,6 +279,110 @@ func TestDockerExecutor_RunVerifier(t *testing.T) {
 	}
 }
 
+func TestDockerExecutor_StressTest(t *testing.T) {
+	dockerExecutor := New(executor.EnableCompiler())
+	res := dockerExecutor.StressTest(&judger.StressTask{
+		ID:         1,
+		Generator:  judger.Program{CodePath: "gen.go"},
+		Reference:  judger.Program{CodePath: "success.go"},
+		Submission: judger.Program{CodePath: "wa.go"},
+		FirstSeed:  1,
+		Seeds:      100,
+		CpuPeriod:  100000,
+		CpuQuota:   50000,
+		Timeout:    1.0,
+		Memory:     20 << 20,
+	})
+	log.Println(res)
+	// 种子1生成的输入中有两数相等且不为0的一行
+	if !res.Found || res.Seed != 1 || res.Verdict != errors.WA {
+		t.Errorf("StressTest() = %v, want WA on seed 1", res)
+	}
+
+	// 提交程序编译失败时没有运行任何种子
+	res = dockerExecutor.StressTest(&judger.StressTask{
+		ID:         2,
+		Generator:  judger.Program{CodePath: "gen.go"},
+		Reference:  judger.Program{CodePath: "success.go"},
+		Submission: judger.Program{CodePath: "ce.go"},
+		FirstSeed:  1,
+		Seeds:      100,
+		Timeout:    1.0,
+		Memory:     20 << 20,
+	})
+	if res.Found || res.Seed != 0 || res.Seeds != 0 || res.Verdict != errors.CE {
+		t.Errorf("StressTest() = %v, want CE without seed", res)
+	}
+}
+
+func TestDockerExecutor_ValidateInputs(t *testing.T) {
+	dockerExecutor := New(executor.EnableCompiler())
+	// 编译 mock/code/validator.go 后放到validator目录下
+	task := &judger.Task{ID: 1, CodePath: "validator.go", ExePath: "validator"}
+	lang, err := executor.TaskLanguage(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dockerExecutor.CompileTask(task, lang); err != nil {
+		t.Fatal(err)
+	}
+	utils.CheckDirectoryExist(fmt.Sprintf("%s/validator", ResourcePath))
+	if err = copyFile(fmt.Sprintf("%s/exe/validator", ResourcePath), fmt.Sprintf("%s/validator/validator", ResourcePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid, err := dockerExecutor.ValidateInputs("validator", []string{"1.txt", "2.txt", "invalid.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []judger.InvalidInput{{Input: "invalid.txt", Msg: "line 3: value out of range"}}
+	if !reflect.DeepEqual(invalid, want) {
+		t.Errorf("ValidateInputs() = %+v, want %+v", invalid, want)
+	}
+}
+
+func TestValidatorMessage(t *testing.T) {
+	var tests = []struct {
+		code int
+		msg  string
+		want string
+	}{
+		{3, "line 2: value out of range\n", "line 2: value out of range"},
+		{1, " \n", "validator exited with code 1"},
+		{124, "", fmt.Sprintf("validator exceeded %v seconds", checkerTimeout)},
+		{143, "", fmt.Sprintf("validator exceeded %v seconds", checkerTimeout)}, // busybox timeout
+	}
+
+	for _, test := range tests {
+		if msg := validatorMessage(test.code, test.msg); msg != test.want {
+			t.Errorf("validatorMessage(%v, %q) = %q, want %q", test.code, test.msg, msg, test.want)
+		}
+	}
+}
+
+func TestRunError(t *testing.T) {
+	var tests = []struct {
+		res     containerResult
+		events  memoryEvents
+		verdict errors.JudgerError
+	}{
+		{containerResult{}, memoryEvents{LimitHit: 1}, errors.AC},
+		{containerResult{StatusCode: 137, OOMKilled: true}, memoryEvents{}, errors.MLE},
+		{containerResult{StatusCode: 137}, memoryEvents{OOMKill: 1}, errors.MLE},
+		{containerResult{StatusCode: 137}, memoryEvents{LimitHit: 2}, errors.MLE},
+		{containerResult{StatusCode: 2}, memoryEvents{LimitHit: 2}, errors.RE},
+		{containerResult{StatusCode: 143}, memoryEvents{LimitHit: 1}, errors.TLE},
+		{containerResult{StatusCode: 159}, memoryEvents{OOMKill: 1}, errors.RF},
+		{containerResult{StatusCode: 1}, memoryEvents{}, errors.UNKNOWN},
+	}
+
+	for _, test := range tests {
+		if err := runError(test.res, test.events, ""); errors.Code(err) != test.verdict {
+			t.Errorf("runError(%+v, %+v) = %v", test.res, test.events, err)
+		}
+	}
+}
+
 func TestSprintf(t *testing.T) {
 	var output = "output"
 	var input = "input"
@@ -173,3 +393,190 @@ func TestSprintf(t *testing.T) {
 			strconv.FormatFloat(2.5, 'f', 4, 32), "1.go", "1.txt")}
 	fmt.Println(strings.Join(strs, " "))
 }
+
+func TestParseStat(t *testing.T) {
+	var tests = []struct {
+		stderr  string
+		msg     string
+		cpuTime time.Duration
+		memory  int64
+		events  memoryEvents
+	}{
+		{"panic: index out of range\n" + statMarker + " v2 12000 3250176 0 0\n", "panic: index out of range\n", 12 * time.Millisecond, 3250176, memoryEvents{}},
+		{statMarker + " v1 12000000 3250176 0 0\n", "", 12 * time.Millisecond, 3250176, memoryEvents{}},
+		{statMarker + " v2 8000 20971520 1 3\n", "", 8 * time.Millisecond, 20971520, memoryEvents{OOMKill: 1, LimitHit: 3}},
+		{"Killed\n", "Killed\n", 0, 0, memoryEvents{}},
+	}
+
+	for _, test := range tests {
+		var res judger.CaseResult
+		msg, events := parseStat(test.stderr, &res)
+		if msg != test.msg || res.CpuTime != test.cpuTime || res.Memory != test.memory || events != test.events {
+			t.Errorf("parseStat(%q) = %q, %v, %v, %+v", test.stderr, msg, res.CpuTime, res.Memory, events)
+		}
+	}
+}
+
+func TestParseStatSince(t *testing.T) {
+	var tests = []struct {
+		stderr  string
+		msg     string
+		cpuTime time.Duration
+		memory  int64
+		events  memoryEvents
+	}{
+		{statMarker + " v2 12000 3250176 0 2\n" + statMarker + " v2 20000 4000000 0 2\n", "", 8 * time.Millisecond, 0, memoryEvents{}},
+		{statMarker + " v2 12000 3250176 1 2\npanic\n" + statMarker + " v2 20000 4000000 2 5\n", "panic\n", 8 * time.Millisecond, 0, memoryEvents{OOMKill: 1, LimitHit: 3}},
+		{statMarker + " v2 8000 20971520 1 3\nKilled\n", "Killed\n", 0, 0, memoryEvents{}},
+	}
+
+	for _, test := range tests {
+		var res judger.CaseResult
+		msg, events := parseStatSince(test.stderr, &res)
+		if msg != test.msg || res.CpuTime != test.cpuTime || res.Memory != test.memory || events != test.events {
+			t.Errorf("parseStatSince(%q) = %q, %v, %v, %+v", test.stderr, msg, res.CpuTime, res.Memory, events)
+		}
+	}
+}
+
+func TestRunnerPool(t *testing.T) {
+	p := &runnerPool{size: 2, maxUses: 10}
+	a, b, c := &runner{id: "a", key: "go"}, &runner{id: "b", key: "python"}, &runner{id: "c", key: "go"}
+	for _, r := range []*runner{a, b} {
+		if evicted := p.put(r); evicted != nil {
+			t.Fatalf("put(%v) evicted %v", r.id, evicted.id)
+		}
+	}
+	// 超出size时删除最早归还的容器
+	if evicted := p.put(c); evicted != a {
+		t.Fatalf("put(c) evicted %v, want a", evicted)
+	}
+	if r := p.take("go"); r != c {
+		t.Errorf("take(go) = %v, want c", r)
+	}
+	if r := p.take("go"); r != nil {
+		t.Errorf("take(go) = %v, want nil", r)
+	}
+
+	p.closed = true
+	if evicted := p.put(a); evicted != a {
+		t.Errorf("put after close evicted %v, want a", evicted)
+	}
+}
+
+// 不依赖docker的容器池，记录被删除的容器
+type fakePool struct {
+	*runnerPool
+	unhealthy map[string]bool
+	resetErr  error
+	removed   []string
+	created   int
+}
+
+func newFakePool(size, maxUses int) *fakePool {
+	f := &fakePool{unhealthy: map[string]bool{}}
+	f.runnerPool = &runnerPool{
+		size:    size,
+		maxUses: maxUses,
+		healthy: func(r *runner) bool { return !f.unhealthy[r.id] },
+		reset:   func(r *runner) error { return f.resetErr },
+		remove:  func(r *runner) { f.removed = append(f.removed, r.id) },
+	}
+	return f
+}
+
+func (f *fakePool) create() (*runner, error) {
+	f.created++
+	return &runner{id: fmt.Sprintf("r%v", f.created), key: "go"}, nil
+}
+
+func TestRunnerPool_Lifecycle(t *testing.T) {
+	var tests = []struct {
+		name    string
+		run     func(f *fakePool) // 对容器池的一系列操作
+		idle    []string          // 最后的空闲容器
+		removed []string
+		created int
+	}{
+		{"reuse", func(f *fakePool) {
+			r, _ := f.get("go", f.create)
+			f.release(r, true)
+			f.get("go", f.create)
+		}, nil, nil, 1},
+		{"max uses", func(f *fakePool) {
+			for i := 0; i < 3; i++ {
+				r, _ := f.get("go", f.create)
+				f.release(r, true)
+			}
+		}, []string{"r2"}, []string{"r1"}, 2},
+		{"not reused after error", func(f *fakePool) {
+			r, _ := f.get("go", f.create)
+			f.release(r, false)
+		}, nil, []string{"r1"}, 1},
+		{"reset failed", func(f *fakePool) {
+			f.resetErr = fmt.Errorf("reset")
+			r, _ := f.get("go", f.create)
+			f.release(r, true)
+		}, nil, []string{"r1"}, 1},
+		{"evict over size", func(f *fakePool) {
+			r1, _ := f.get("go", f.create)
+			r2, _ := f.get("go", f.create)
+			r3, _ := f.get("go", f.create)
+			f.release(r1, true)
+			f.release(r2, true)
+			f.release(r3, true)
+		}, []string{"r2", "r3"}, []string{"r1"}, 3},
+		{"replace unhealthy", func(f *fakePool) {
+			r, _ := f.get("go", f.create)
+			f.release(r, true)
+			f.unhealthy["r1"] = true
+			r, _ = f.get("go", f.create)
+			f.release(r, true)
+		}, []string{"r2"}, []string{"r1"}, 2},
+		{"close", func(f *fakePool) {
+			r1, _ := f.get("go", f.create)
+			r2, _ := f.get("go", f.create)
+			f.release(r1, true)
+			f.close()
+			f.release(r2, true)
+		}, nil, []string{"r1", "r2"}, 2},
+	}
+
+	for _, test := range tests {
+		f := newFakePool(2, 2)
+		test.run(f)
+		var idle []string
+		for _, r := range f.idle {
+			idle = append(idle, r.id)
+		}
+		if !reflect.DeepEqual(idle, test.idle) || !reflect.DeepEqual(f.removed, test.removed) || f.created != test.created {
+			t.Errorf("%v: idle %v, removed %v, created %v", test.name, idle, f.removed, f.created)
+		}
+	}
+}
+
+// 输出限制不同的任务使用不同的容器，容器的fsize在创建时设置
+func TestRunnerPool_OutputLimit(t *testing.T) {
+	d := &DockerExecutor{}
+	lang := &language.Language{Name: "go"}
+	small := executor.RunTask{Task: &judger.Task{Memory: 64 << 20, OutputLimit: 1 << 10}, Lang: lang}
+	large := executor.RunTask{Task: &judger.Task{Memory: 64 << 20, OutputLimit: 1 << 20}, Lang: lang}
+
+	f := newFakePool(2, 10)
+	create := func(task executor.RunTask) func() (*runner, error) {
+		return func() (*runner, error) {
+			f.created++
+			return &runner{id: fmt.Sprintf("r%v", f.created), key: d.runnerKey(task)}, nil
+		}
+	}
+	r1, _ := f.get(d.runnerKey(small), create(small))
+	f.release(r1, true)
+	r2, _ := f.get(d.runnerKey(large), create(large))
+	if r2 == r1 || f.created != 2 {
+		t.Errorf("runner for output limit %v reused for %v", small.OutputLimit, large.OutputLimit)
+	}
+	f.release(r2, true)
+	if r, _ := f.get(d.runnerKey(small), create(small)); r != r1 {
+		t.Errorf("get(small) = %v, want %v", r.id, r1.id)
+	}
+}
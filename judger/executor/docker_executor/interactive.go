@@ -0,0 +1,99 @@
+package docker_executor
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+	"tgoj/judger/utils"
+	"tgoj/judger/verifier"
+	"time"
+)
+
+// 交互题的第i个用例：interactor 和提交的程序分别在两个容器中运行，各自有独立的资源限制
+// 两者的标准输入输出通过共享目录中的命名管道交叉连接，命名管道由interactor容器创建，提交的程序以非root用户运行，需要允许所有用户读写
+//
+//	interactor: /interactor /input/1.txt /output/1.txt /answer/1.txt < /pipe/out > /pipe/in
+//	提交的程序: /exe > /pipe/out < /pipe/in
+//
+// 提交程序的TLE、MLE优先，其次是interactor的结果(兼容testlib的退出码)，最后是提交程序的其他运行错误
+func (d *DockerExecutor) RunInteractive(task executor.RunTask, i int, caseRes *judger.CaseResult) error {
+	tc := task.TestCases[i]
+	inputDir, inputFile := filepath.Split(tc.InputPath)
+	outputDir, outputFile := filepath.Split(tc.OutputPath)
+	answerDir, answerFile := filepath.Split(tc.AnswerPath)
+
+	// 保证目录存在
+	if outputDir != "" {
+		utils.CheckDirectoryExist(fmt.Sprintf("%s/output/%s", ResourcePath, outputDir))
+	}
+	pipeDir := fmt.Sprintf("%s/interact/%v_%v_%v", ResourcePath, task.ID, i, time.Now().UnixNano())
+	utils.CheckDirectoryExist(pipeDir)
+	defer os.RemoveAll(pipeDir)
+
+	timeout := task.Lang.Timeout(task.Timeout)
+
+	var wg sync.WaitGroup
+	var interactorRes containerResult
+	var interactorErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		interactorRes, interactorErr = d.runContainer(&container.Config{
+			Cmd: []string{"sh", "-c", fmt.Sprintf(
+				"mkfifo -m 666 /pipe/in /pipe/out && timeout %v sh -c 'exec /interactor /input/%s /output/%s /answer/%s < /pipe/out > /pipe/in'",
+				strconv.FormatFloat(timeout+checkerTimeout, 'f', 4, 32), inputFile, outputFile, answerFile)},
+			Image:           d.runnerContainerImage,
+			NetworkDisabled: true,
+		}, &container.HostConfig{
+			Binds: []string{
+				fmt.Sprintf("%s/interactor/%s:/interactor:ro", ResourcePath, task.Interactor),
+				fmt.Sprintf("%s:/pipe", pipeDir),
+				fmt.Sprintf("%s/input/%s:/input:ro", ResourcePath, inputDir),
+				fmt.Sprintf("%s/output/%s:/output", ResourcePath, outputDir),
+				fmt.Sprintf("%s/answer/%s:/answer:ro", ResourcePath, answerDir),
+			},
+			Resources: container.Resources{
+				Memory:     checkerMemory,
+				MemorySwap: checkerMemory,
+			},
+		})
+	}()
+
+	// 等待interactor创建命名管道，最多等待5秒；先以写方式打开/pipe/out，与interactor打开管道的顺序对应，避免死锁
+	res, err := d.runContainer(
+		d.runnerConfig(task, withStat(fmt.Sprintf(
+			"n=0; until [ -p /pipe/in ] && [ -p /pipe/out ] || [ $n -ge 500 ]; do sleep 0.01; n=$((n+1)); done; "+
+				"timeout %v sh -c 'exec %s > /pipe/out < /pipe/in'",
+			strconv.FormatFloat(timeout, 'f', 4, 32), task.Lang.RunCommand("/exe")))),
+		runnerHostConfig(task,
+			fmt.Sprintf("%s:/exe:ro", exeHostPath(task.Task, task.Lang)),
+			fmt.Sprintf("%s:/pipe", pipeDir),
+		))
+	wg.Wait()
+
+	caseRes.WallTime = res.WallTime
+	if err != nil {
+		return err
+	}
+	if interactorErr != nil {
+		return interactorErr
+	}
+
+	msg, events := parseStat(res.Stderr, caseRes)
+	runErr := runError(res, events, msg)
+	if errors.IsError(runErr, errors.TLE) || errors.IsError(runErr, errors.MLE) {
+		return runErr
+	}
+
+	// interactor 的信息输出到stderr
+	if err = verifier.TestlibResult(int(interactorRes.StatusCode), interactorRes.Stderr); err != nil {
+		return err
+	}
+	return runErr
+}
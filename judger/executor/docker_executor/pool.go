@@ -0,0 +1,392 @@
+package docker_executor
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+	"tgoj/judger/utils"
+	"time"
+)
+
+const (
+	runnerKeepalive = "while true; do sleep 3600; done" // 运行容器的主进程，只用于保持容器运行
+	runnerWorkDir   = "/work"                           // 每次使用时准备的目录，包含 exe、input、output
+	runnerExecGrace = 5 * time.Second                   // 程序的时间限制之外，等待exec结束的时间
+	runnerResetTime = 10 * time.Second                  // 重置容器的时间限制
+	// 杀死上一次运行残留的进程，清空 /tmp、/dev/shm 和 /dev/mqueue；以运行用户执行，kill -1 不会杀死容器的主进程
+	runnerResetCmd = "kill -9 -1 2>/dev/null; " +
+		"rm -rf /tmp/* /tmp/.[!.]* /tmp/..?* /dev/shm/* /dev/shm/.[!.]* /dev/shm/..?* /dev/mqueue/*"
+)
+
+// 预先创建的运行容器，通过exec在 /work 中运行用例
+type runner struct {
+	id   string
+	key  string // 语言、镜像和资源限制相同的任务可以使用同一个容器
+	dir  string // /work 在宿主机上的目录
+	uses int
+}
+
+// 空闲的运行容器，按归还的先后排序
+type runnerPool struct {
+	sync.Mutex
+	size    int // 空闲容器的数量上限，超出时删除最早归还的容器
+	maxUses int // 每个容器最多使用的次数
+	idle    []*runner
+	closed  bool
+
+	// 对容器的操作，由DockerExecutor提供，测试时可以替换
+	healthy func(r *runner) bool  // 容器是否仍可以使用
+	reset   func(r *runner) error // 清理上一次运行
+	remove  func(r *runner)       // 删除容器
+}
+
+// 启用运行容器池: 最多保留size个空闲容器，每个容器使用maxUses次后删除
+// 不在 executor.Executor 中，通过 WithRunnerPool 设置
+func (d *DockerExecutor) SetRunnerPool(size, maxUses int) error {
+	if size <= 0 || maxUses <= 0 {
+		return fmt.Errorf("runner pool size and max uses must be greater than 0, but received %v, %v", size, maxUses)
+	}
+	d.pool = &runnerPool{
+		size:    size,
+		maxUses: maxUses,
+		healthy: d.runnerHealthy,
+		reset:   d.resetRunner,
+		remove:  d.removeRunner,
+	}
+	return nil
+}
+
+func WithRunnerPool(size, maxUses int) executor.Option {
+	return func(e executor.Executor) error {
+		d, ok := e.(*DockerExecutor)
+		if !ok {
+			return fmt.Errorf("runner pool is only supported by DockerExecutor, but received %T", e)
+		}
+		return d.SetRunnerPool(size, maxUses)
+	}
+}
+
+// 运行容器的key，创建容器时已经设置了用户、seccomp、资源限制、fsize(输出限制)等，这些都相同的任务才能共用容器
+func (d *DockerExecutor) runnerKey(task executor.RunTask) string {
+	return fmt.Sprintf("%s %s %v %v %v %v", task.Lang.Name, d.runnerImage(task.Lang), task.Memory, task.CpuPeriod, task.CpuQuota,
+		task.MaxOutput())
+}
+
+// 在池中的容器内运行第i个用例，输出移动到outputPath
+// 容器的cgroup统计是创建以来的累计值，运行前后各输出一次统计信息
+func (d *DockerExecutor) runPooled(task executor.RunTask, i int, outputPath string, caseRes *judger.CaseResult) error {
+	key := d.runnerKey(task)
+	r, err := d.pool.get(key, func() (*runner, error) { return d.createRunner(task, key) })
+	if err != nil {
+		log.Println(task.ID, err)
+		return err
+	}
+
+	input := fmt.Sprintf("%s/input/%s", ResourcePath, task.TestCases[i].InputPath)
+	if err = r.prepare(exeHostPath(task.Task, task.Lang), input); err != nil {
+		d.pool.release(r, false)
+		return err
+	}
+
+	work := runnerWorkDir
+	cmd := fmt.Sprintf("%s; %s", statScript,
+		withStat(fmt.Sprintf("%s > %s/output", runPipeline(task, work+"/input", work+"/exe"), work)))
+	res, err := d.execRunner(r, cmd, time.Duration(task.Lang.Timeout(task.Timeout)*float64(time.Second))+runnerExecGrace)
+	caseRes.WallTime = res.WallTime
+	if err == nil {
+		err = os.Rename(filepath.Join(r.dir, "output"), outputPath)
+	}
+	if err != nil {
+		log.Println(task.ID, err)
+		d.pool.release(r, false)
+		return err
+	}
+
+	msg, events := parseStatSince(res.Stderr, caseRes)
+	err = outputError(task, outputPath, res, events, msg)
+	// 出现任何异常判定的容器都不再使用
+	d.pool.release(r, err == nil)
+	return err
+}
+
+// 容器是否仍在运行
+func (d *DockerExecutor) runnerHealthy(r *runner) bool {
+	inspect, err := d.cli.ContainerInspect(context.Background(), r.id)
+	if err == nil && inspect.State != nil && inspect.State.Running {
+		return true
+	}
+	log.Println(r.id, "unhealthy runner:", err)
+	return false
+}
+
+// 创建并启动一个运行容器，隔离设置与一次性的运行容器相同，只挂载该容器自己的 /work
+func (d *DockerExecutor) createRunner(task executor.RunTask, key string) (*runner, error) {
+	poolDir := fmt.Sprintf("%s/pool", ResourcePath)
+	utils.CheckDirectoryExist(poolDir)
+	dir, err := ioutil.TempDir(poolDir, "runner")
+	if err != nil {
+		return nil, err
+	}
+	// 目录属于root，运行用户只能写入预先创建的输出文件
+	if err = os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	r := &runner{key: key, dir: dir}
+	resp, err := d.cli.ContainerCreate(context.Background(), d.runnerConfig(task, runnerKeepalive),
+		runnerHostConfig(task, fmt.Sprintf("%s:%s", dir, runnerWorkDir)), nil, nil, "")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	r.id = resp.ID
+	if err = d.cli.ContainerStart(context.Background(), r.id, types.ContainerStartOptions{}); err != nil {
+		d.removeRunner(r)
+		return nil, err
+	}
+	return r, nil
+}
+
+// 杀死残留的进程，清空 /tmp、/dev/shm、/dev/mqueue 和 /work
+func (d *DockerExecutor) resetRunner(r *runner) error {
+	res, err := d.execRunner(r, runnerResetCmd, runnerResetTime)
+	if err != nil {
+		log.Println(r.id, err)
+		return err
+	}
+	if res.StatusCode != 0 {
+		err = fmt.Errorf("reset runner: exited with %v: %s", res.StatusCode, res.Stderr)
+		log.Println(r.id, err)
+		return err
+	}
+	return r.clean()
+}
+
+func (d *DockerExecutor) removeRunner(r *runner) {
+	d.removeContainer(r.id)
+	if err := os.RemoveAll(r.dir); err != nil {
+		log.Println(r.id, err)
+	}
+}
+
+// 在运行容器中以容器的用户执行cmd，工作目录为 /work
+// 超过timeout仍未结束时(例如程序的子进程仍持有stderr)杀死容器，视为TLE
+func (d *DockerExecutor) execRunner(r *runner, cmd string, timeout time.Duration) (res containerResult, err error) {
+	resp, err := d.cli.ContainerExecCreate(context.Background(), r.id, types.ExecConfig{
+		Cmd:          []string{"sh", "-c", cmd},
+		WorkingDir:   runnerWorkDir,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	response, err := d.cli.ContainerExecAttach(context.Background(), resp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return
+	}
+	defer response.Close()
+
+	stdout, stderr := utils.NewHeadTailBuffer(stderrLimit, stderrLimit), utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	copyErrCh := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, response.Reader)
+		copyErrCh <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err = <-copyErrCh:
+	case <-timer.C:
+		res.Killed = true
+		if err := d.cli.ContainerKill(context.Background(), r.id, "KILL"); err != nil {
+			log.Println(r.id, err)
+		}
+		response.Close()
+		<-copyErrCh
+	}
+	res.WallTime = time.Since(start)
+	if res.Killed {
+		return res, errors.New(errors.TLE, fmt.Sprintf("exec not finished in %v", timeout))
+	}
+	if err != nil {
+		return
+	}
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+
+	// 输出结束时exec可能还未被标记为结束
+	for {
+		inspect, err := d.cli.ContainerExecInspect(context.Background(), resp.ID)
+		if err != nil {
+			return res, err
+		}
+		if !inspect.Running {
+			res.StatusCode = int64(inspect.ExitCode)
+			return res, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (d *DockerExecutor) closeRunnerPool() {
+	if d.pool != nil {
+		d.pool.close()
+	}
+}
+
+// 取出可以运行该任务的容器，没有时调用create创建新的容器
+// 取出时检查容器是否仍在运行，不健康的容器直接删除
+func (p *runnerPool) get(key string, create func() (*runner, error)) (*runner, error) {
+	for {
+		r := p.take(key)
+		if r == nil {
+			return create()
+		}
+		if p.healthy(r) {
+			return r, nil
+		}
+		p.remove(r)
+	}
+}
+
+// 归还容器: 异常判定、达到使用次数 或 重置失败时删除容器，否则放回池中
+func (p *runnerPool) release(r *runner, reuse bool) {
+	r.uses++
+	if reuse && r.uses < p.maxUses {
+		reuse = p.reset(r) == nil
+	} else {
+		reuse = false
+	}
+	if !reuse {
+		p.remove(r)
+		return
+	}
+
+	if evicted := p.put(r); evicted != nil {
+		p.remove(evicted)
+	}
+}
+
+// 删除池中所有空闲容器，之后归还的容器都会被删除
+func (p *runnerPool) close() {
+	p.Lock()
+	idle := p.idle
+	p.idle, p.closed = nil, true
+	p.Unlock()
+
+	for _, r := range idle {
+		p.remove(r)
+	}
+}
+
+// 取出最近归还的、key相同的空闲容器
+func (p *runnerPool) take(key string) *runner {
+	p.Lock()
+	defer p.Unlock()
+
+	for i := len(p.idle) - 1; i >= 0; i-- {
+		if r := p.idle[i]; r.key == key {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			return r
+		}
+	}
+	return nil
+}
+
+// 放回空闲容器，返回需要删除的容器
+func (p *runnerPool) put(r *runner) *runner {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return r
+	}
+	p.idle = append(p.idle, r)
+	if len(p.idle) <= p.size {
+		return nil
+	}
+	evicted := p.idle[0]
+	p.idle = p.idle[1:]
+	return evicted
+}
+
+// 准备本次运行的 /work: 可执行文件、输入 和 允许所有用户写入的输出文件
+func (r *runner) prepare(exe, input string) error {
+	if err := r.clean(); err != nil {
+		return err
+	}
+	if err := linkTree(exe, filepath.Join(r.dir, "exe")); err != nil {
+		return err
+	}
+	if err := linkTree(input, filepath.Join(r.dir, "input")); err != nil {
+		return err
+	}
+	return createOutputFile(filepath.Join(r.dir, "output"))
+}
+
+// 删除 /work 中的所有文件
+func (r *runner) clean() error {
+	files, err := ioutil.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err = os.RemoveAll(filepath.Join(r.dir, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 把src(文件或目录)硬链接到dst，不能硬链接 或 其他用户可写的文件复制一份，避免程序通过链接修改原文件
+func linkTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if info.Mode()&0022 == 0 && os.Link(path, target) == nil {
+			return nil
+		}
+		return copyFile(path, target, info.Mode().Perm()&^0022)
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
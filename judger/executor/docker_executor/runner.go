@@ -0,0 +1,74 @@
+package docker_executor
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+	"os"
+	"tgoj/judger"
+	"tgoj/judger/executor"
+	"tgoj/judger/language"
+	"tgoj/judger/seccomp"
+)
+
+// 运行容器中 /tmp 的tmpfs，根文件系统只读时程序仍可以写临时文件
+const runnerTmpfs = "rw,nosuid,nodev,size=16m"
+
+// 运行提交程序的容器配置，以 language.Security 指定的用户运行，默认禁用网络
+func (d *DockerExecutor) runnerConfig(task executor.RunTask, cmd string) *container.Config {
+	sec := task.Lang.Security
+	return &container.Config{
+		Cmd:             []string{"sh", "-c", cmd},
+		Image:           d.runnerImage(task.Lang),
+		User:            sec.RunUser(),
+		Env:             []string{"HOME=/tmp"},
+		NetworkDisabled: !sec.Network,
+	}
+}
+
+// 运行提交程序的容器的资源限制和隔离设置：
+// 禁用网络、限制进程数量、去掉所有capabilities、no-new-privileges、seccomp、只读根文件系统，可以按语言放宽
+// 写入的单个文件不能超过输出限制加1字节(RLIMIT_FSIZE)，程序绕过head直接写输出文件时也无法超出
+// 输出通过attach读取，不使用日志驱动，避免docker在宿主机上保存程序的全部stderr
+func runnerHostConfig(task executor.RunTask, binds ...string) *container.HostConfig {
+	sec := task.Lang.Security
+	pids := sec.Pids()
+	fsize := task.MaxOutput() + 1
+	hostConfig := &container.HostConfig{
+		Binds: binds,
+		Resources: container.Resources{
+			Memory:     task.Memory,
+			MemorySwap: task.Memory,
+			CPUPeriod:  task.CpuPeriod,
+			CPUQuota:   task.CpuQuota,
+			PidsLimit:  &pids,
+			Ulimits:    []*units.Ulimit{{Name: "fsize", Soft: fsize, Hard: fsize}},
+		},
+		CapDrop:        []string{"ALL"},
+		CapAdd:         sec.CapAdd,
+		SecurityOpt:    []string{"no-new-privileges", seccomp.New(task.Lang.Syscalls...).SecurityOpt()},
+		ReadonlyRootfs: !sec.WritableRoot,
+		Tmpfs:          map[string]string{"/tmp": runnerTmpfs},
+		LogConfig:      container.LogConfig{Type: "none"},
+	}
+	if !sec.Network {
+		hostConfig.NetworkMode = "none"
+	}
+	return hostConfig
+}
+
+// validator等不属于某种语言的程序，使用默认的隔离设置 和 运行容器的镜像
+func toolTask(memory int64) executor.RunTask {
+	return executor.RunTask{Task: &judger.Task{Memory: memory}, Lang: &language.Language{}}
+}
+
+// 预先创建输出文件并允许所有用户写入，运行容器中的非root用户不能在输出目录中创建文件
+// 运行容器只挂载这一个文件，不能写同一目录下其他用例的输出
+func createOutputFile(name string) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	// 不受umask影响
+	return os.Chmod(name, 0666)
+}
@@ -0,0 +1,105 @@
+package docker_executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"tgoj/judger"
+	"time"
+)
+
+// 可执行文件运行结束后，容器内的 sh 读取本容器的cgroup文件，将CPU时间、峰值内存和内存事件输出到stderr的最后一行
+// 容器在退出后cgroup会被立即删除，无法在宿主机上读取，因此在容器内读取
+//
+//	cgroup v2: cpu.stat 中的 usage_usec(微秒)，memory.peak(内核5.19以下没有该文件，退化为 memory.current)，
+//	           memory.events 中的 oom_kill 和 max(内存使用达到限制的次数)
+//	cgroup v1: cpuacct.usage(纳秒)，memory.max_usage_in_bytes，
+//	           memory.oom_control 中的 oom_kill(内核4.13以上) 和 memory.failcnt
+//
+// 统计结果包含 sh、timeout 等命令的开销，约几百KB
+const statMarker = "__TGOJ_STAT__"
+
+const statScript = `if [ -f /sys/fs/cgroup/cpu.stat ]; then ` +
+	`echo "` + statMarker + ` v2 $(grep usage_usec /sys/fs/cgroup/cpu.stat | cut -d' ' -f2) ` +
+	`$(cat /sys/fs/cgroup/memory.peak 2>/dev/null || cat /sys/fs/cgroup/memory.current) ` +
+	`$( (grep '^oom_kill ' /sys/fs/cgroup/memory.events || echo 'oom_kill 0') | cut -d' ' -f2) ` +
+	`$( (grep '^max ' /sys/fs/cgroup/memory.events || echo 'max 0') | cut -d' ' -f2)" >&2; ` +
+	`else ` +
+	`echo "` + statMarker + ` v1 $(cat /sys/fs/cgroup/cpuacct/cpuacct.usage) ` +
+	`$(cat /sys/fs/cgroup/memory/memory.max_usage_in_bytes) ` +
+	`$( (grep '^oom_kill ' /sys/fs/cgroup/memory/memory.oom_control || echo 'oom_kill 0') | cut -d' ' -f2) ` +
+	`$(cat /sys/fs/cgroup/memory/memory.failcnt)" >&2; ` +
+	`fi`
+
+// 容器cgroup的内存事件
+type memoryEvents struct {
+	OOMKill  int64 // 被OOM killer杀死的进程数
+	LimitHit int64 // 内存使用达到限制的次数
+}
+
+// 在cmd执行结束后输出统计信息，并保留cmd的退出码
+func withStat(cmd string) string {
+	return fmt.Sprintf("%s; code=$?; %s; exit $code", cmd, statScript)
+}
+
+// 从stderr中解析统计信息记录到res，返回去掉统计信息后的stderr 及 内存事件
+func parseStat(stderr string, res *judger.CaseResult) (string, memoryEvents) {
+	var events memoryEvents
+	idx := strings.LastIndex(stderr, statMarker)
+	if idx < 0 {
+		return stderr, events
+	}
+
+	line := stderr[idx:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+
+	var values [4]int64
+	fields := strings.Fields(line)
+	if len(fields) != len(values)+2 {
+		return stderr[:idx], events
+	}
+	for i := range values {
+		v, err := strconv.ParseInt(fields[i+2], 10, 64)
+		if err != nil {
+			return stderr[:idx], events
+		}
+		values[i] = v
+	}
+
+	if fields[1] == "v2" {
+		res.CpuTime = time.Duration(values[0]) * time.Microsecond
+	} else {
+		res.CpuTime = time.Duration(values[0])
+	}
+	res.Memory = values[1]
+	events.OOMKill, events.LimitHit = values[2], values[3]
+	return stderr[:idx], events
+}
+
+// 池中的运行容器的cgroup统计是容器创建以来的累计值，程序运行前后各输出一次统计信息
+// CPU时间和内存事件取两次的差值；峰值内存无法在容器内重置，会包含之前的运行(其他提交)的使用量，因此不记录，Memory 为0
+func parseStatSince(stderr string, res *judger.CaseResult) (string, memoryEvents) {
+	var before judger.CaseResult
+	var beforeEvents memoryEvents
+	if strings.HasPrefix(stderr, statMarker) {
+		end := strings.IndexByte(stderr, '\n') + 1
+		if end == 0 {
+			end = len(stderr)
+		}
+		_, beforeEvents = parseStat(stderr[:end], &before)
+		stderr = stderr[end:]
+	}
+	// 没有运行后的统计信息
+	if !strings.Contains(stderr, statMarker) {
+		return stderr, memoryEvents{}
+	}
+
+	msg, events := parseStat(stderr, res)
+	res.Memory = 0
+	res.CpuTime -= before.CpuTime
+	events.OOMKill -= beforeEvents.OOMKill
+	events.LimitHit -= beforeEvents.LimitHit
+	return msg, events
+}
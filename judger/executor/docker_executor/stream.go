@@ -0,0 +1,88 @@
+package docker_executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+)
+
+// 流式校验的结果
+type streamResult struct {
+	passed int
+	err    error
+}
+
+// 运行第i个用例并同时校验，程序的stdout通过attach连接直接交给校验器，不写入输出文件
+// 校验器发现第一个不同时立即杀死容器；校验器给出错误时结果为该错误，校验通过时才按容器的运行结果判定
+func (d *DockerExecutor) RunStream(task executor.RunTask, i int, caseRes *judger.CaseResult) error {
+	inputDir, inputFile := filepath.Split(task.TestCases[i].InputPath)
+	answer := fmt.Sprintf("%s/answer/%s", ResourcePath, task.TestCases[i].AnswerPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	resultCh := make(chan streamResult, 1)
+	go func() {
+		passed, err := task.StreamVerifier.VerifyStream(pr, answer)
+		if err != nil {
+			cancel()
+		}
+		resultCh <- streamResult{passed, err}
+		// 继续读取剩余的输出，避免容器阻塞在写stdout上
+		io.Copy(ioutil.Discard, pr)
+	}()
+
+	stdout := &countWriter{w: pw}
+	res, err := d.runContainerStream(ctx,
+		d.runnerConfig(task, withStat(runPipeline(task, "/input/"+inputFile, "/exe"))),
+		runnerHostConfig(task,
+			fmt.Sprintf("%s:/exe:ro", exeHostPath(task.Task, task.Lang)),
+			fmt.Sprintf("%s/input/%s:/input:ro", ResourcePath, inputDir),
+		), stdout)
+	pw.Close()
+	verified := <-resultCh
+
+	caseRes.WallTime = res.WallTime
+	if err != nil {
+		log.Println(task.ID, err)
+		return err
+	}
+
+	// 被提前杀死时没有统计信息
+	var msg string
+	var events memoryEvents
+	if !res.Killed {
+		msg, events = parseStat(res.Stderr, caseRes)
+	}
+	// 校验器给出错误时以校验结果为准：发现不同时容器可能已经在退出，是否被杀死、退出码都不确定
+	if verified.err != nil {
+		return executor.VerifyResult(task.Task, i, caseRes, verified.passed, verified.err)
+	}
+
+	if outputLimit := task.MaxOutput(); stdout.n > outputLimit {
+		return errors.New(errors.OLE, fmt.Sprintf("output exceeds %v bytes", outputLimit))
+	}
+	if err = runError(res, events, msg); err != nil {
+		return err
+	}
+	return executor.VerifyResult(task.Task, i, caseRes, verified.passed, nil)
+}
+
+// 记录写入的字节数
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,127 @@
+package docker_executor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+	"tgoj/judger/utils"
+	"tgoj/judger/verifier"
+)
+
+// 对拍，依次运行每个种子，找到第一个提交程序未通过的种子后停止
+// 生成的输入和输出保存在 $Resource/input/stress/<ID>/、$Resource/output/stress/<ID>/ 下，通过的种子的文件会被删除
+// 同步执行，与 Execute 的评测任务共用编译容器
+func (d *DockerExecutor) StressTest(st *judger.StressTask) judger.StressResult {
+	res := judger.StressResult{ID: st.ID, Verdict: errors.AC}
+	fail := func(err error) judger.StressResult {
+		// 校验器返回的 *verifier.Mismatch 等不是 errors.Error，需要通过 verifier.Verdict 得到判定
+		res.Verdict, res.Error = verifier.Verdict(err), err
+		return res
+	}
+
+	generator, err := d.prepareProgram(st.Task(st.Generator))
+	if err != nil {
+		return fail(errors.New(errors.ENV, fmt.Sprintf("generator: %v", err)))
+	}
+	reference, err := d.prepareProgram(st.Task(st.Reference))
+	if err != nil {
+		return fail(errors.New(errors.ENV, fmt.Sprintf("reference: %v", err)))
+	}
+	submission, err := d.prepareProgram(st.Task(st.Submission))
+	if err != nil {
+		// 提交程序编译失败，没有运行任何种子，只返回CE
+		return fail(err)
+	}
+
+	dir := fmt.Sprintf("stress/%d", st.ID)
+	utils.CheckDirectoryExist(fmt.Sprintf("%s/input/%s", ResourcePath, dir))
+	utils.CheckDirectoryExist(fmt.Sprintf("%s/output/%s", ResourcePath, dir))
+
+	for n := 0; n < st.Seeds; n++ {
+		seed := st.FirstSeed + int64(n)
+		res.Seeds = n + 1
+		tc := judger.TestCase{
+			InputPath:  fmt.Sprintf("%s/%d.txt", dir, seed),
+			AnswerPath: fmt.Sprintf("%s/%d.ans", dir, seed),
+			OutputPath: fmt.Sprintf("%s/%d.out", dir, seed),
+		}
+		input := fmt.Sprintf("%s/input/%s", ResourcePath, tc.InputPath)
+		output := fmt.Sprintf("%s/output/%s", ResourcePath, tc.OutputPath)
+		// 标准程序的输出也写入output目录
+		answer := fmt.Sprintf("%s/output/%s", ResourcePath, tc.AnswerPath)
+
+		if err = d.generate(generator, seed, tc.InputPath); err != nil {
+			return fail(errors.New(errors.ENV, fmt.Sprintf("generator failed on seed %v: %v", seed, err)))
+		}
+
+		var caseRes judger.CaseResult
+		reference.TestCases = []judger.TestCase{{InputPath: tc.InputPath, OutputPath: tc.AnswerPath}}
+		if err = d.RunCase(reference, 0, &caseRes); err != nil {
+			return fail(errors.New(errors.ENV, fmt.Sprintf("reference failed on seed %v: %v", seed, err)))
+		}
+
+		submission.TestCases = []judger.TestCase{tc}
+		if err = d.RunCase(submission, 0, &caseRes); err == nil {
+			_, err = d.VerifyFiles(submission.Task, input, output, answer)
+		}
+		if err != nil {
+			res.Found, res.Seed, res.Input = true, seed, tc.InputPath
+			return fail(err)
+		}
+
+		for _, f := range []string{input, output, answer} {
+			if err := os.Remove(f); err != nil {
+				log.Println(st.ID, err)
+			}
+		}
+	}
+	return res
+}
+
+// 编译对拍中的一个程序，解释型语言进行语法检查
+func (d *DockerExecutor) prepareProgram(task *judger.Task) (executor.RunTask, error) {
+	lang, err := executor.TaskLanguage(task)
+	if err != nil {
+		return executor.RunTask{}, err
+	}
+	t := executor.RunTask{Task: task, Lang: lang}
+	if lang.Interpreted() {
+		return t, d.SyntaxCheck(t)
+	}
+
+	task.ExePath = lang.ExePath(task.CodePath)
+	return t, d.CompileTask(task, lang)
+}
+
+// 以种子作为参数运行生成器，输出写入input目录下的inputPath，隔离设置与运行提交的程序相同
+func (d *DockerExecutor) generate(generator executor.RunTask, seed int64, inputPath string) error {
+	_, inputFile := filepath.Split(inputPath)
+	outputLimit := generator.MaxOutput()
+	hostPath := fmt.Sprintf("%s/input/%s", ResourcePath, inputPath)
+	if err := createOutputFile(hostPath); err != nil {
+		return err
+	}
+	res, err := d.runContainer(
+		d.runnerConfig(generator, withStat(fmt.Sprintf("set -o pipefail; timeout %v %s %d | head -c %d > /output/%s",
+			strconv.FormatFloat(generator.Lang.Timeout(generator.Timeout), 'f', 4, 32), generator.Lang.RunCommand("/exe"),
+			seed, outputLimit+1, inputFile))),
+		runnerHostConfig(generator,
+			fmt.Sprintf("%s:/exe:ro", exeHostPath(generator.Task, generator.Lang)),
+			fmt.Sprintf("%s:/output/%s", hostPath, inputFile),
+		))
+	if err != nil {
+		return err
+	}
+
+	var caseRes judger.CaseResult
+	msg, events := parseStat(res.Stderr, &caseRes)
+	if info, statErr := os.Stat(hostPath); statErr == nil && info.Size() > outputLimit {
+		return errors.New(errors.OLE, fmt.Sprintf("input exceeds %v bytes", outputLimit))
+	}
+	return runError(res, events, msg)
+}
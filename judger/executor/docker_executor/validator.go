@@ -0,0 +1,62 @@
+package docker_executor
+
+import (
+	"fmt"
+	"strings"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+)
+
+// coreutils timeout 命令超时的退出码，busybox 的timeout 以SIGTERM结束程序，退出码为143
+const timeoutExitCode = 124
+
+// 在运行容器中对每个输入文件运行题目的validator，返回所有未通过的文件，用于发布题目前检查测试数据
+// validator 为相对validator目录的路径，inputs 为相对input目录的路径
+// validator 从stdin读取输入，退出码为0表示通过(兼容testlib)，信息为stderr，stderr为空时为stdout
+// err 不为nil 表示无法运行validator
+func (d *DockerExecutor) ValidateInputs(validator string, inputs []string) ([]judger.InvalidInput, error) {
+	var invalid []judger.InvalidInput
+	for _, input := range inputs {
+		code, msg, err := d.runValidator(validator, input)
+		if err != nil {
+			return invalid, err
+		}
+		if code == 0 {
+			continue
+		}
+
+		invalid = append(invalid, judger.InvalidInput{Input: input, Msg: validatorMessage(code, msg)})
+	}
+	return invalid, nil
+}
+
+// validator 未通过时的信息，超时时给出时间限制
+func validatorMessage(code int, msg string) string {
+	if code == timeoutExitCode || errors.ExitedCode2JudgerError[int64(code)] == errors.TLE {
+		return fmt.Sprintf("validator exceeded %v seconds", checkerTimeout)
+	}
+	if msg = strings.TrimSpace(msg); msg == "" {
+		return fmt.Sprintf("validator exited with code %v", code)
+	}
+	return msg
+}
+
+// validator 与checker使用相同的资源限制，隔离设置与运行提交的程序相同，validator和输入都以只读方式挂载
+func (d *DockerExecutor) runValidator(validator, input string) (int, string, error) {
+	task := toolTask(checkerMemory)
+	res, err := d.runContainer(
+		d.runnerConfig(task, fmt.Sprintf("timeout %v /validator < /data/input", checkerTimeout)),
+		runnerHostConfig(task,
+			fmt.Sprintf("%s/validator/%s:/validator:ro", ResourcePath, validator),
+			fmt.Sprintf("%s/input/%s:/data/input:ro", ResourcePath, input),
+		))
+	if err != nil {
+		return 0, "", err
+	}
+
+	msg := res.Stderr
+	if strings.TrimSpace(msg) == "" {
+		msg = res.Stdout
+	}
+	return int(res.StatusCode), msg, nil
+}
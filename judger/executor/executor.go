@@ -19,6 +19,7 @@ type Executor interface {
 	SetTaskChan(taskCh <-chan *judger.Task) error
 
 	// 编译阶段的goroutine数量  如果设置了n>0 且 没有启动编译容器，会自动启动编译容器
+	// 以下三个方法可以在运行时调用，增加或减少goroutine数量，减少时多余的goroutine处理完当前任务后退出
 	SetCompileConcurrency(n int) error
 
 	// 执行阶段的goroutine数量
@@ -27,6 +28,9 @@ type Executor interface {
 	// 校验阶段的goroutine数量
 	SetVerifyConcurrency(n int) error
 
+	// 按channel中等待的任务数量自动调整三个阶段的goroutine数量，Destroy时停止
+	SetAutoscaler(a Autoscaler) error
+
 	// 启动编译容器
 	EnableCompiler() error
 
@@ -0,0 +1,49 @@
+package native_executor
+
+import (
+	"fmt"
+	"strings"
+	"tgoj/judger/utils"
+	"tgoj/judger/verifier"
+	"time"
+)
+
+const (
+	checkerTimeout = 10        // checker的时间限制，单位秒
+	checkerMemory  = 256 << 20 // checker的内存限制
+	checkerPids    = 128       // checker的进程(线程)数量限制
+)
+
+var _ verifier.Sandbox = (*NativeExecutor)(nil)
+
+// 在沙箱中执行checker，checker、输入、输出、答案都以只读方式挂载
+// checker 为相对checker目录的路径，其余为宿主机上的路径
+func (n *NativeExecutor) RunChecker(checker, inputFileName, outputFileName, answerFileName string) (int, string, error) {
+	stdout, stderr := utils.NewHeadTailBuffer(stderrLimit, stderrLimit), utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	res, err := n.sandbox.run(sandboxConfig{
+		Rootfs: n.runnerRootfs,
+		Args:   []string{"/sandbox/checker", "/sandbox/data/input", "/sandbox/data/output", "/sandbox/data/answer"},
+		Mounts: []mount{
+			{Source: fmt.Sprintf("%s/checker/%s", ResourcePath, checker), Target: "checker"},
+			{Source: inputFileName, Target: "data/input"},
+			{Source: outputFileName, Target: "data/output"},
+			{Source: answerFileName, Target: "data/answer"},
+		},
+		Tmpfs:   runTmpfs,
+		Memory:  checkerMemory,
+		Pids:    checkerPids,
+		Timeout: checkerTimeout * time.Second,
+		Stdout:  stdout,
+		Stderr:  stderr,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	// testlib 把信息输出到stderr
+	msg := stderr.String()
+	if strings.TrimSpace(msg) == "" {
+		msg = stdout.String()
+	}
+	return res.exitCode(), msg, nil
+}
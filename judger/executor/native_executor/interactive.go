@@ -0,0 +1,118 @@
+package native_executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+	"tgoj/judger/utils"
+	"tgoj/judger/verifier"
+	"time"
+)
+
+// 交互题的第i个用例：interactor 和提交的程序分别在两个沙箱中运行，各自有独立的资源限制
+// 两者的标准输入输出通过两对管道交叉连接
+//
+//	interactor: /sandbox/interactor input output answer < 程序的stdout > 程序的stdin
+//
+// 提交程序的TLE、MLE优先，其次是interactor的结果(兼容testlib的退出码)，最后是提交程序的其他运行错误
+func (n *NativeExecutor) RunInteractive(task executor.RunTask, i int, caseRes *judger.CaseResult) error {
+	if err := n.checkTask(task); err != nil {
+		return err
+	}
+	tc := task.TestCases[i]
+	outputPath := fmt.Sprintf("%s/output/%s", ResourcePath, tc.OutputPath)
+	// 保证目录存在，输出文件需要由interactor(nobody)写入
+	utils.CheckDirectoryExist(filepath.Dir(outputPath))
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	output.Close()
+	if err = os.Chown(outputPath, sandboxUid, sandboxGid); err != nil {
+		return err
+	}
+
+	// 程序 -> interactor，interactor -> 程序
+	toInteractor, fromProgram, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	toProgram, fromInteractor, err := os.Pipe()
+	if err != nil {
+		toInteractor.Close()
+		fromProgram.Close()
+		return err
+	}
+	// 沙箱启动后关闭本进程中的管道，一方退出后另一方才能读到EOF 或 收到SIGPIPE
+	closeFiles := func(files ...*os.File) func() {
+		return func() {
+			for _, f := range files {
+				f.Close()
+			}
+		}
+	}
+	closeInteractorFiles, closeProgramFiles := closeFiles(toInteractor, fromInteractor), closeFiles(toProgram, fromProgram)
+	defer closeInteractorFiles()
+	defer closeProgramFiles()
+
+	timeout := seconds(task.Lang.Timeout(task.Timeout))
+
+	var wg sync.WaitGroup
+	var interactorRes sandboxResult
+	var interactorErr error
+	interactorStderr := utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer closeInteractorFiles()
+		interactorRes, interactorErr = n.sandbox.run(sandboxConfig{
+			Rootfs: n.runnerRootfs,
+			Args:   []string{"/sandbox/interactor", "/sandbox/input", "/sandbox/output", "/sandbox/answer"},
+			Mounts: []mount{
+				{Source: fmt.Sprintf("%s/interactor/%s", ResourcePath, task.Interactor), Target: "interactor"},
+				{Source: fmt.Sprintf("%s/input/%s", ResourcePath, tc.InputPath), Target: "input"},
+				{Source: outputPath, Target: "output", Writable: true},
+				{Source: fmt.Sprintf("%s/answer/%s", ResourcePath, tc.AnswerPath), Target: "answer"},
+			},
+			Tmpfs:   runTmpfs,
+			Memory:  checkerMemory,
+			Pids:    checkerPids,
+			Timeout: timeout + checkerTimeout*time.Second,
+			Stdin:   toInteractor,
+			Stdout:  fromInteractor,
+			Stderr:  interactorStderr,
+			OnStart: closeInteractorFiles,
+		})
+	}()
+
+	stderr := utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	config := n.runConfig(task, toProgram, fromProgram, stderr)
+	config.OnStart = closeProgramFiles
+	res, err := n.sandbox.run(config)
+	closeProgramFiles()
+	wg.Wait()
+
+	caseRes.WallTime = res.WallTime
+	if err != nil {
+		return err
+	}
+	if interactorErr != nil {
+		return interactorErr
+	}
+
+	caseRes.CpuTime, caseRes.Memory = res.CpuTime, res.Memory
+	runErr := runError(res, stderr.String())
+	if errors.IsError(runErr, errors.TLE) || errors.IsError(runErr, errors.MLE) {
+		return runErr
+	}
+
+	// interactor 的信息输出到stderr
+	if err = verifier.TestlibResult(interactorRes.exitCode(), interactorStderr.String()); err != nil {
+		return err
+	}
+	return runErr
+}
@@ -0,0 +1,332 @@
+// Package native_executor 不依赖docker，直接使用Linux命名空间、cgroup v2 和 rlimit 隔离运行提交的程序
+package native_executor
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+	"tgoj/judger/language"
+	"tgoj/judger/seccomp"
+	"tgoj/judger/utils"
+	"time"
+)
+
+// 编译时的PATH，与golang镜像相同
+const compilePath = "/usr/local/go/bin:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+const (
+	DefaultCgroupRoot  = "/sys/fs/cgroup/tgoj"
+	NoCgroup           = "none"    // Cgroup 环境变量为该值时不使用cgroup
+	syntaxCheckTimeout = 10        // 语法检查的时间限制，单位秒
+	compileTimeout     = 60        // 编译的时间限制，单位秒
+	compileMemory      = 1 << 30   // 编译的内存限制
+	compileTmpfs       = 256 << 20 // 编译时 /tmp 的大小
+	runTmpfs           = 16 << 20  // 运行程序时 /tmp 的大小
+	stderrLimit        = 4 << 10   // 保留的stderr大小
+)
+
+var (
+	ResourcePath string
+	CgroupRoot   string
+)
+
+func init() {
+	// 存放评测相关文件的目录，与DockerExecutor相同
+	ResourcePath = os.Getenv("Resource")
+	// 每次运行在该cgroup下创建子cgroup，需要cgroup v2；为none时不使用cgroup
+	CgroupRoot = os.Getenv("Cgroup")
+	switch CgroupRoot {
+	case "":
+		CgroupRoot = DefaultCgroupRoot
+	case NoCgroup:
+		CgroupRoot = ""
+	}
+}
+
+var _ executor.Executor = (*NativeExecutor)(nil)
+
+var _ executor.Backend = (*NativeExecutor)(nil)
+
+// 与DockerExecutor相同，由 executor.Pipeline 分为编译、运行、校验 三个阶段，支持相同的Task字段和判定
+// 编译和运行都在沙箱中进行，rootfs 为宿主机上的目录，需要包含编译器、解释器等运行环境，Language中的镜像不会被使用
+// 需要以root运行
+type NativeExecutor struct {
+	sync.Mutex
+	*executor.Pipeline
+
+	sandbox        *sandbox
+	compilerRootfs string // 编译使用的rootfs
+	runnerRootfs   string // 运行使用的rootfs
+}
+
+/****  Initialization      *****/
+// 参数为编译使用的rootfs目录
+func (n *NativeExecutor) SetCompilerContainer(rootfs string) error {
+	n.compilerRootfs = rootfs
+	return nil
+}
+
+// 参数为运行使用的rootfs目录
+func (n *NativeExecutor) SetRunnerContainer(rootfs string) error {
+	n.runnerRootfs = rootfs
+	return nil
+}
+
+// 每次运行在root下创建子cgroup，为空时不使用cgroup，只有rlimit限制：
+// 设置了内存限制的任务判定为ENV，进程数量由 RLIMIT_NPROC 限制(同一用户的所有进程共用)
+func (n *NativeExecutor) SetCgroup(root string) error {
+	if root != "" {
+		if err := setupCgroup(root); err != nil {
+			return err
+		}
+	}
+	n.sandbox.cgroupRoot = root
+	return nil
+}
+
+func WithCgroup(root string) executor.Option {
+	return func(e executor.Executor) error {
+		n, ok := e.(*NativeExecutor)
+		if !ok {
+			return fmt.Errorf("cgroup is only supported by NativeExecutor, but received %T", e)
+		}
+		return n.SetCgroup(root)
+	}
+}
+
+// 编译在沙箱中进行，不需要启动编译容器，只在rootfs中创建挂载点 并创建编译缓存目录
+func (n *NativeExecutor) EnableCompiler() error {
+	if err := prepareRootfs(n.compilerRootfs); err != nil {
+		return err
+	}
+	utils.CheckDirectoryExist(compileCacheDir())
+	return os.Chown(compileCacheDir(), sandboxUid, sandboxGid)
+}
+
+// 编译缓存目录，各次编译共享，与编译容器中的缓存相同，避免每次编译都重新编译标准库
+func compileCacheDir() string {
+	return fmt.Sprintf("%s/cache", ResourcePath)
+}
+
+// rootfs 默认为 $Resource/rootfs；cgroup 默认为 CgroupRoot，可以通过 WithCgroup 修改或禁用
+func New(opts ...executor.Option) *NativeExecutor {
+	n := &NativeExecutor{
+		sandbox:        &sandbox{cgroupRoot: CgroupRoot},
+		compilerRootfs: fmt.Sprintf("%s/rootfs", ResourcePath),
+		runnerRootfs:   fmt.Sprintf("%s/rootfs", ResourcePath),
+	}
+	n.Pipeline = executor.NewPipeline(n)
+
+	for _, opt := range opts {
+		if err := opt(n); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := n.SetCgroup(n.sandbox.cgroupRoot); err != nil {
+		panic(fmt.Errorf("%v, use Cgroup=%v or WithCgroup(\"\") to run without cgroup", err, NoCgroup))
+	}
+	// 在rootfs中创建挂载点
+	for _, rootfs := range []string{n.compilerRootfs, n.runnerRootfs} {
+		if err := prepareRootfs(rootfs); err != nil {
+			panic(err)
+		}
+	}
+	return n
+}
+
+// 在沙箱中把 CodePath 编译为 ExePath，编译失败返回CE
+// 源代码只读挂载，编译结果先写入nobody可写的临时目录，编译成功后移动到 ExePath
+func (n *NativeExecutor) CompileTask(task *judger.Task, lang *language.Language) error {
+	exePath := fmt.Sprintf("%s/exe/%s", ResourcePath, task.ExePath)
+	utils.CheckDirectoryExist(filepath.Dir(exePath))
+	outDir, err := ioutil.TempDir(filepath.Dir(exePath), ".compile")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(outDir)
+	if err = os.Chown(outDir, sandboxUid, sandboxGid); err != nil {
+		return err
+	}
+
+	src, exe := filepath.Base(task.CodePath), filepath.Base(task.ExePath)
+	output := utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	res, err := n.sandbox.run(sandboxConfig{
+		Rootfs: n.compilerRootfs,
+		// go build -o /sandbox/out/success /sandbox/code/success.go
+		Args: []string{"sh", "-c", lang.CompileCommand("/sandbox/code/"+src, "/sandbox/out/"+exe)},
+		Env:  []string{"PATH=" + compilePath, "HOME=/tmp", "XDG_CACHE_HOME=/sandbox/cache"},
+		Mounts: []mount{
+			{Source: fmt.Sprintf("%s/code/%s", ResourcePath, task.CodePath), Target: "code/" + src},
+			{Source: outDir, Target: "out", Writable: true},
+			{Source: compileCacheDir(), Target: "cache", Writable: true},
+		},
+		Tmpfs:   compileTmpfs,
+		Memory:  compileMemory,
+		Timeout: compileTimeout * time.Second,
+		Stdout:  output,
+		Stderr:  output,
+	})
+	if err != nil {
+		log.Println(task.ID, err)
+		return err
+	}
+	if res.TimedOut {
+		return errors.New(errors.CE, fmt.Sprintf("compile time exceeds %vs", compileTimeout))
+	}
+	if res.ExitCode != 0 || res.Signal != 0 {
+		return errors.New(errors.CE, output.String())
+	}
+
+	if err = os.RemoveAll(exePath); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(outDir, exe), exePath)
+}
+
+// 运行第i个用例，运行时间等信息记录在caseRes中
+func (n *NativeExecutor) RunCase(task executor.RunTask, i int, caseRes *judger.CaseResult) error {
+	if err := n.checkTask(task); err != nil {
+		return err
+	}
+	stdin, err := inputReader(task.TestCases[i].InputPath)
+	if err != nil {
+		return err
+	}
+	outputPath := fmt.Sprintf("%s/output/%s", ResourcePath, task.TestCases[i].OutputPath)
+	// 保证目录存在
+	utils.CheckDirectoryExist(filepath.Dir(outputPath))
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	stderr := utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	res, err := n.sandbox.run(n.runConfig(task, stdin, output, stderr))
+	caseRes.WallTime = res.WallTime
+	if err != nil {
+		log.Println(task.ID, err)
+		return err
+	}
+	caseRes.CpuTime, caseRes.Memory = res.CpuTime, res.Memory
+
+	// 输出文件的大小限制比输出限制多1个字节，用于判断是否超出限制
+	if info, statErr := output.Stat(); statErr == nil && info.Size() > task.MaxOutput() {
+		return errors.New(errors.OLE, fmt.Sprintf("output exceeds %v bytes", task.MaxOutput()))
+	}
+	return runError(res, stderr.String())
+}
+
+// 检查任务能否在该NativeExecutor中按要求的限制运行，不能时判定为ENV
+func (n *NativeExecutor) checkTask(task executor.RunTask) error {
+	if err := checkSecurity(task.Lang); err != nil {
+		return err
+	}
+	return checkCgroup(n.sandbox.cgroupRoot, task.Task)
+}
+
+// 没有cgroup时不能限制内存，也无法判定MLE，设置了内存限制的任务判定为ENV，而不是不限制内存运行
+func checkCgroup(cgroupRoot string, task *judger.Task) error {
+	if cgroupRoot == "" && task.Memory > 0 {
+		return errors.New(errors.ENV, fmt.Sprintf("memory limit %v requires cgroup v2, but NativeExecutor runs without cgroup", task.Memory))
+	}
+	return nil
+}
+
+// 沙箱的rootfs是各次运行共享的宿主机目录，不能可写；程序以非root用户运行，不保留capabilities
+// 因此不支持 Security 中的 WritableRoot 和 CapAdd，使用时判定为ENV，而不是忽略
+func checkSecurity(lang *language.Language) error {
+	if lang.Security.WritableRoot || len(lang.Security.CapAdd) > 0 {
+		return errors.New(errors.ENV, fmt.Sprintf("language %v: WritableRoot and CapAdd are not supported by NativeExecutor", lang.Name))
+	}
+	return nil
+}
+
+// 运行提交程序的沙箱配置，/sandbox/exe 为可执行文件，解释型语言为源代码文件
+func (n *NativeExecutor) runConfig(task executor.RunTask, stdin io.Reader, stdout, stderr io.Writer) sandboxConfig {
+	return sandboxConfig{
+		Rootfs:    n.runnerRootfs,
+		Args:      strings.Fields(task.Lang.RunCommand("/sandbox/exe")),
+		Env:       []string{"HOME=/tmp"},
+		User:      task.Lang.Security.RunUser(),
+		Network:   task.Lang.Security.Network,
+		Mounts:    []mount{{Source: exeHostPath(task.Task, task.Lang), Target: "exe"}},
+		Tmpfs:     runTmpfs,
+		Memory:    task.Memory,
+		CpuPeriod: task.CpuPeriod,
+		CpuQuota:  task.CpuQuota,
+		Pids:      task.Lang.Security.Pids(),
+		Timeout:   seconds(task.Lang.Timeout(task.Timeout)),
+		FileSize:  task.MaxOutput() + 1,
+		Seccomp:   seccomp.New(task.Lang.Syscalls...),
+		Stdin:     stdin,
+		Stdout:    stdout,
+		Stderr:    stderr,
+	}
+}
+
+// 与DockerExecutor相同，输入文件中的空白字符合并为一个空格后作为程序的标准输入
+func inputReader(inputPath string) (io.Reader, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("%s/input/%s", ResourcePath, inputPath))
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(strings.Join(strings.Fields(string(content)), " ") + "\n"), nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// 解释型语言在运行用例前检查语法，失败视为CE
+func (n *NativeExecutor) SyntaxCheck(task executor.RunTask) error {
+	if task.Lang.CheckCmd == "" {
+		return nil
+	}
+	if err := checkCgroup(n.sandbox.cgroupRoot, task.Task); err != nil {
+		return err
+	}
+
+	output := utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	res, err := n.sandbox.run(sandboxConfig{
+		Rootfs:    n.runnerRootfs,
+		Args:      []string{"sh", "-c", task.Lang.CheckCommand("/sandbox/exe")},
+		Mounts:    []mount{{Source: exeHostPath(task.Task, task.Lang), Target: "exe"}},
+		Tmpfs:     runTmpfs,
+		Memory:    task.Memory,
+		CpuPeriod: task.CpuPeriod,
+		CpuQuota:  task.CpuQuota,
+		Pids:      task.Lang.Security.Pids(),
+		Timeout:   syntaxCheckTimeout * time.Second,
+		Stdout:    output,
+		Stderr:    output,
+	})
+	if err != nil {
+		log.Println(task.ID, err)
+		return err
+	}
+	if res.TimedOut || res.ExitCode != 0 || res.Signal != 0 {
+		return errors.New(errors.CE, output.String())
+	}
+	return nil
+}
+
+// 沙箱中 /sandbox/exe 在宿主机上的路径，解释型语言直接使用源代码文件
+func exeHostPath(task *judger.Task, lang *language.Language) string {
+	if lang.Interpreted() {
+		return fmt.Sprintf("%s/code/%s", ResourcePath, task.CodePath)
+	}
+	return fmt.Sprintf("%s/exe/%s", ResourcePath, task.ExePath)
+}
+
+func (n *NativeExecutor) Resource() string {
+	return ResourcePath
+}
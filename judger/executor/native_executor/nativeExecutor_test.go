@@ -0,0 +1,216 @@
+package native_executor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"testing"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+	"tgoj/judger/language"
+	"time"
+)
+
+func init() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+}
+
+// 需要以root运行，Rootfs 环境变量为包含go、sh的rootfs目录
+func TestNativeExecutor_Run(t *testing.T) {
+	rootfs := os.Getenv("Rootfs")
+	if runtime.GOOS != "linux" || os.Getuid() != 0 || rootfs == "" || ResourcePath == "" {
+		t.Skip("native executor requires root on linux, Rootfs and Resource")
+	}
+	// 测试环境不一定有可用的cgroup v2，没有cgroup时不能设置内存限制
+	cgroup := cgroupRoot()
+	var memory int64
+	if cgroup != "" {
+		memory = 64 << 20
+	}
+
+	taskCh, resultCh := make(chan *judger.Task), make(chan judger.Result, 100)
+	nativeExecutor := New(
+		executor.WithCompilerContainer(rootfs),
+		executor.WithRunnerContainer(rootfs),
+		WithCgroup(cgroup),
+		executor.WithResultChan(resultCh),
+		executor.WithTaskChan(taskCh),
+		executor.WithCompileConcurrency(3),
+		executor.WithRunConcurrency(3),
+		executor.WithVerifyConcurrency(3),
+	)
+	go nativeExecutor.Execute()
+
+	// answer 为空时使用 1.txt、2.txt 两个用例，cgroup 为true时需要cgroup才能得到该结果
+	var tests = []struct {
+		codePath string
+		language string
+		answer   string
+		stream   bool
+		cgroup   bool
+		verdict  errors.JudgerError
+	}{
+		{"1//success.go", "", "", false, false, errors.AC},
+		{"out_of_bound.go", "", "", false, false, errors.RE},
+		{"ce.go", "", "", false, false, errors.CE},
+		{"timeout.go", "", "", false, false, errors.TLE},
+		{"ole.go", "", "", false, false, errors.OLE},
+		{"success.py", "python", "", false, false, errors.AC},
+		{"socket.go", "", "", false, false, errors.RF},
+		{"success.go", "", "", true, false, errors.AC},
+		{"wa.go", "", "", true, false, errors.WA},
+		{"lockdown.go", "", "lockdown.txt", false, false, errors.AC},
+		{"threads.go", "", "", false, true, errors.RE},
+	}
+	for i, test := range tests {
+		testCases := []judger.TestCase{
+			{InputPath: "1.txt", AnswerPath: "1.txt", OutputPath: fmt.Sprintf("native/%v_1.txt", i)},
+			{InputPath: "2.txt", AnswerPath: "2.txt", OutputPath: fmt.Sprintf("native/%v_2.txt", i)},
+		}
+		if test.answer != "" {
+			testCases = []judger.TestCase{{InputPath: "1.txt", AnswerPath: test.answer, OutputPath: fmt.Sprintf("native/%v.txt", i)}}
+		}
+		taskCh <- &judger.Task{
+			ID:          int64(i),
+			CodePath:    test.codePath,
+			Language:    test.language,
+			TestCases:   testCases,
+			CpuPeriod:   100000,
+			CpuQuota:    50000,
+			Timeout:     1.0,
+			Memory:      memory,
+			OutputLimit: 1 << 20,
+			Stream:      test.stream,
+			Status:      judger.CREATED,
+		}
+	}
+
+	verdicts := map[int64]errors.JudgerError{}
+	for range tests {
+		res := <-resultCh
+		log.Println(res)
+		verdicts[res.ID] = res.Verdict
+	}
+	for i, test := range tests {
+		if test.cgroup && cgroup == "" {
+			continue
+		}
+		if verdicts[int64(i)] != test.verdict {
+			t.Errorf("%v: verdict %v, want %v", test.codePath, verdicts[int64(i)], test.verdict)
+		}
+	}
+
+	if err := nativeExecutor.Destroy(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 运行时调整各阶段的goroutine数量，减少时正在处理的任务不受影响
+func TestNativeExecutor_Resize(t *testing.T) {
+	rootfs := os.Getenv("Rootfs")
+	if runtime.GOOS != "linux" || os.Getuid() != 0 || rootfs == "" || ResourcePath == "" {
+		t.Skip("native executor requires root on linux, Rootfs and Resource")
+	}
+	cgroup := cgroupRoot()
+	var memory int64
+	if cgroup != "" {
+		memory = 64 << 20
+	}
+
+	taskCh, resultCh := make(chan *judger.Task), make(chan judger.Result, 100)
+	nativeExecutor := New(
+		executor.WithRunnerContainer(rootfs),
+		WithCgroup(cgroup),
+		executor.WithResultChan(resultCh),
+		executor.WithTaskChan(taskCh),
+		executor.WithRunConcurrency(1),
+		executor.WithVerifyConcurrency(1),
+		executor.WithAutoscaler(executor.Autoscaler{Max: 4, Interval: 50 * time.Millisecond}),
+	)
+	go nativeExecutor.Execute()
+
+	const n = 8
+	for i := 0; i < n; i++ {
+		taskCh <- &judger.Task{
+			ID:        int64(i),
+			CodePath:  "success.py",
+			Language:  "python",
+			TestCases: []judger.TestCase{{InputPath: "1.txt", AnswerPath: "1.txt", OutputPath: fmt.Sprintf("resize/%v.txt", i)}},
+			Timeout:   1.0,
+			Memory:    memory,
+			Status:    judger.CREATED,
+		}
+		switch i {
+		case 2:
+			if err := nativeExecutor.SetRunConcurrency(3); err != nil {
+				t.Fatal(err)
+			}
+		case 5:
+			if err := nativeExecutor.SetRunConcurrency(1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		res := <-resultCh
+		if res.Verdict != errors.AC {
+			t.Errorf("task %v: %v", res.ID, res)
+		}
+	}
+	if _, workers, _ := nativeExecutor.Concurrency(); workers < 1 || workers > 4 {
+		t.Errorf("run workers = %v", workers)
+	}
+
+	if err := nativeExecutor.Destroy(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckSecurity(t *testing.T) {
+	var tests = []struct {
+		security language.Security
+		ok       bool
+	}{
+		{language.Security{}, true},
+		{language.Security{Network: true, PidsLimit: 8, User: "1000:1000"}, true},
+		{language.Security{WritableRoot: true}, false},
+		{language.Security{CapAdd: []string{"SYS_PTRACE"}}, false},
+	}
+
+	for _, test := range tests {
+		err := checkSecurity(&language.Language{Name: "go", Security: test.security})
+		if (err == nil) != test.ok || (err != nil && errors.Code(err) != errors.ENV) {
+			t.Errorf("checkSecurity(%+v) = %v", test.security, err)
+		}
+	}
+}
+
+func TestCheckCgroup(t *testing.T) {
+	var tests = []struct {
+		cgroupRoot string
+		memory     int64
+		ok         bool
+	}{
+		{DefaultCgroupRoot, 64 << 20, true},
+		{"", 0, true},
+		{"", 64 << 20, false},
+	}
+
+	for _, test := range tests {
+		err := checkCgroup(test.cgroupRoot, &judger.Task{Memory: test.memory})
+		if (err == nil) != test.ok || (err != nil && errors.Code(err) != errors.ENV) {
+			t.Errorf("checkCgroup(%q, %v) = %v", test.cgroupRoot, test.memory, err)
+		}
+	}
+}
+
+// 可用的cgroup，没有cgroup v2时为空
+func cgroupRoot() string {
+	if err := setupCgroup(CgroupRoot); err != nil {
+		return ""
+	}
+	return CgroupRoot
+}
@@ -0,0 +1,116 @@
+package native_executor
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+	"tgoj/judger/errors"
+	"tgoj/judger/seccomp"
+	"time"
+)
+
+// 沙箱中程序的用户，nobody
+const (
+	sandboxUid = 65534
+	sandboxGid = 65534
+)
+
+// 挂载到沙箱 /sandbox 目录下的宿主机文件或目录
+type mount struct {
+	Source   string // 宿主机上的路径
+	Target   string // 相对 /sandbox 的路径
+	Writable bool   // 默认只读
+}
+
+// 在沙箱中运行一个命令的配置
+type sandboxConfig struct {
+	Rootfs    string   // 沙箱的根目录，只读
+	Args      []string // 在沙箱中执行的命令，不经过shell，第一个参数在PATH中查找
+	Env       []string
+	User      string // 运行命令的用户，格式为 uid:gid，为空时为nobody
+	Network   bool   // 使用宿主机的网络，默认在新的net命名空间中，只有未启用的lo
+	Mounts    []mount
+	Tmpfs     int64            // 挂载到 /tmp 的tmpfs大小，单位 byte，为0时不挂载
+	Memory    int64            // 内存限制，单位 byte，为0时不限制
+	CpuPeriod int64            // 同 cgroup cpu.max，CpuQuota 为0时不限制
+	CpuQuota  int64            //
+	Pids      int64            // 进程数量限制，为0时不限制
+	Timeout   time.Duration    // 墙钟时间限制，同时设置 RLIMIT_CPU
+	FileSize  int64            // 单个文件的大小限制 RLIMIT_FSIZE，为0时不限制
+	Seccomp   *seccomp.Profile // 允许的系统调用，为nil时不限制
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	Kill      <-chan struct{} // 关闭时立即结束沙箱，可选
+	OnStart   func()          // 沙箱进程启动后调用，可选，用于关闭已传给沙箱的文件
+}
+
+// 解析 uid:gid 格式的用户，为空时为nobody
+func parseUser(user string) (uid, gid uint32, err error) {
+	if user == "" {
+		return sandboxUid, sandboxGid, nil
+	}
+	parts := strings.Split(user, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid user %q, expect uid:gid", user)
+	}
+	u, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid user %q: %v", user, err)
+	}
+	g, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid user %q: %v", user, err)
+	}
+	return uint32(u), uint32(g), nil
+}
+
+// 沙箱的运行结果
+type sandboxResult struct {
+	ExitCode         int
+	Signal           int // 程序被信号杀死时的信号
+	TimedOut         bool
+	Killed           bool // 因 Kill 被提前结束
+	CpuLimitExceeded bool // 因 RLIMIT_CPU 被杀死
+	FileSizeExceeded bool // 因 RLIMIT_FSIZE 被杀死
+	SyscallBlocked   bool // 调用了白名单之外的系统调用，被seccomp杀死
+	CpuTime          time.Duration
+	WallTime         time.Duration
+	Memory           int64 // 峰值内存，单位 byte
+	OOMKill          int64 // 被OOM killer杀死的进程数
+	LimitHit         int64 // 内存使用达到限制的次数
+}
+
+// 程序运行结果对应的错误，与DockerExecutor的判定一致，msg为程序的stderr
+func runError(res sandboxResult, msg string) error {
+	switch {
+	case res.TimedOut || res.CpuLimitExceeded:
+		return errors.New(errors.TLE, msg)
+	case res.ExitCode == 0 && res.Signal == 0:
+		return nil
+	case res.SyscallBlocked:
+		return errors.New(errors.RF, msg)
+	// 内存使用达到过限制只作为辅助判断，程序被SIGKILL杀死时才视为MLE
+	case res.OOMKill > 0 || res.LimitHit > 0 && res.Signal == int(syscall.SIGKILL):
+		return errors.New(errors.MLE, msg)
+	case res.FileSizeExceeded:
+		return errors.New(errors.OLE, msg)
+	case res.Signal != 0:
+		return errors.New(errors.RE, fmt.Sprintf("killed by signal %v: %v", res.Signal, msg))
+	}
+
+	if v, ok := errors.ExitedCode2JudgerError[int64(res.ExitCode)]; ok {
+		return errors.New(v, msg)
+	}
+	return errors.New(errors.UNKNOWN, msg)
+}
+
+// 与shell相同的退出码，被信号杀死时为 128+信号
+func (r sandboxResult) exitCode() int {
+	if r.Signal != 0 {
+		return 128 + r.Signal
+	}
+	return r.ExitCode
+}
@@ -0,0 +1,569 @@
+// +build linux
+
+package native_executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/pkg/reexec"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"tgoj/judger/errors"
+	"tgoj/judger/seccomp"
+	"time"
+)
+
+// 沙箱的init进程通过重新执行当前程序启动，argv[0] 为该名称
+const sandboxInitName = "tgoj-sandbox-init"
+
+// 沙箱内的PATH
+const sandboxPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// 从宿主机绑定到沙箱 /dev 下的设备
+var sandboxDevices = []string{"null", "zero", "urandom"}
+
+func init() {
+	reexec.Register(sandboxInitName, sandboxInit)
+	// 作为沙箱的init进程运行时，sandboxInit 执行结束后退出，不会执行main
+	if reexec.Init() {
+		os.Exit(0)
+	}
+}
+
+// 在新的 pid、mount、net、ipc、uts 命名空间中运行命令，资源限制来自cgroup v2 和 rlimit
+//
+//	judger ── init(沙箱内pid为1，root) ── 程序(nobody)
+//
+// init 通过fd 3读取配置，挂载rootfs并pivot_root，设置rlimit后以非root用户(默认nobody)运行程序，
+// 程序结束后把退出状态写入fd 4；杀死init时，内核会杀死命名空间内的所有进程
+// init 在启动程序前才通过fd 5(cgroup目录)加入cgroup，之前分配的内存和使用的CPU不计入程序的统计
+type sandbox struct {
+	cgroupRoot string // 每次运行在该目录下创建子cgroup，为空时不使用cgroup，只有rlimit限制
+}
+
+// init 进程的配置
+type initConfig struct {
+	Rootfs  string
+	Args    []string
+	Env     []string
+	Uid     uint32
+	Gid     uint32
+	Mounts  []mount
+	Tmpfs   int64
+	Rlimits []rlimit
+	Seccomp *seccomp.Profile
+	Cgroup  bool  // fd 5 为cgroup目录
+	Pids    int64 // 程序的进程数量限制，init 加入cgroup后加上自身的线程数写入pids.max
+}
+
+type rlimit struct {
+	Resource int
+	Cur, Max uint64
+}
+
+// init 返回的程序退出状态
+type initStatus struct {
+	ExitCode int
+	Signal   int
+	CpuTime  time.Duration // rusage 中的CPU时间，未使用cgroup时使用
+	MaxRSS   int64         // rusage 中的峰值内存，未使用cgroup 或 内核不支持memory.peak时使用
+	Error    string        // init 出错，程序没有运行
+}
+
+var cgroupSeq int64
+
+func (s *sandbox) run(config sandboxConfig) (res sandboxResult, err error) {
+	uid, gid, err := parseUser(config.User)
+	if err != nil {
+		return res, errors.New(errors.ENV, err.Error())
+	}
+	cg, err := s.newCgroup(config)
+	if err != nil {
+		return res, errors.New(errors.ENV, fmt.Sprintf("create cgroup: %v", err))
+	}
+	defer cg.remove()
+
+	configReader, configWriter, err := os.Pipe()
+	if err != nil {
+		return res, err
+	}
+	defer configWriter.Close()
+	statusReader, statusWriter, err := os.Pipe()
+	if err != nil {
+		configReader.Close()
+		return res, err
+	}
+	defer statusReader.Close()
+	cgDir, err := cg.open()
+	if err != nil {
+		configReader.Close()
+		statusWriter.Close()
+		return res, errors.New(errors.ENV, fmt.Sprintf("open cgroup: %v", err))
+	}
+
+	cmd := reexec.Command(sandboxInitName)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = config.Stdin, config.Stdout, config.Stderr
+	cmd.ExtraFiles = []*os.File{configReader, statusWriter}
+	if cgDir != nil {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, cgDir)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWPID | syscall.CLONE_NEWNS | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	if !config.Network {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	start := time.Now()
+	err = cmd.Start()
+	configReader.Close()
+	statusWriter.Close()
+	if cgDir != nil {
+		cgDir.Close()
+	}
+	if err != nil {
+		return res, errors.New(errors.ENV, fmt.Sprintf("start sandbox: %v", err))
+	}
+	if config.OnStart != nil {
+		config.OnStart()
+	}
+
+	err = json.NewEncoder(configWriter).Encode(initConfig{
+		Rootfs:  config.Rootfs,
+		Args:    config.Args,
+		Env:     config.Env,
+		Uid:     uid,
+		Gid:     gid,
+		Mounts:  config.Mounts,
+		Tmpfs:   config.Tmpfs,
+		Rlimits: rlimits(config, cg != nil),
+		Seccomp: config.Seccomp,
+		Cgroup:  cgDir != nil,
+		Pids:    config.Pids,
+	})
+	configWriter.Close()
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return res, errors.New(errors.ENV, fmt.Sprintf("start sandbox: %v", err))
+	}
+
+	var timedOut, killed int32
+	timer := time.AfterFunc(config.Timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		cmd.Process.Kill()
+	})
+	done := make(chan struct{})
+	if config.Kill != nil {
+		go func() {
+			select {
+			case <-config.Kill:
+				atomic.StoreInt32(&killed, 1)
+				cmd.Process.Kill()
+			case <-done:
+			}
+		}()
+	}
+
+	var status initStatus
+	decodeErr := json.NewDecoder(statusReader).Decode(&status)
+	cmd.Wait()
+	timer.Stop()
+	close(done)
+
+	res.WallTime = time.Since(start)
+	res.TimedOut, res.Killed = atomic.LoadInt32(&timedOut) == 1, atomic.LoadInt32(&killed) == 1
+	if !res.TimedOut && !res.Killed {
+		if decodeErr != nil {
+			return res, errors.New(errors.ENV, fmt.Sprintf("sandbox init: %v", decodeErr))
+		}
+		if status.Error != "" {
+			return res, errors.New(errors.ENV, fmt.Sprintf("sandbox init: %v", status.Error))
+		}
+	}
+
+	res.ExitCode, res.Signal = status.ExitCode, status.Signal
+	res.CpuLimitExceeded = status.Signal == int(syscall.SIGXCPU)
+	res.FileSizeExceeded = status.Signal == int(syscall.SIGXFSZ)
+	res.SyscallBlocked = status.Signal == int(syscall.SIGSYS)
+	res.CpuTime, res.Memory = status.CpuTime, status.MaxRSS
+	cg.stat(&res)
+	return res, nil
+}
+
+// syscall 包中没有定义，amd64、arm64 上都为6
+const rlimitNproc = 6
+
+// 除内存、CPU外的资源限制，内存和CPU由cgroup限制；未使用cgroup时进程数量由 RLIMIT_NPROC 限制
+func rlimits(config sandboxConfig, cgroup bool) []rlimit {
+	limits := []rlimit{{Resource: syscall.RLIMIT_CORE}}
+	if config.Timeout > 0 {
+		// CPU时间超过墙钟时间限制时同样视为TLE，避免未使用cgroup时无法限制
+		cpu := uint64(config.Timeout/time.Second) + 1
+		limits = append(limits, rlimit{Resource: syscall.RLIMIT_CPU, Cur: cpu, Max: cpu + 1})
+	}
+	if config.FileSize > 0 {
+		limits = append(limits, rlimit{Resource: syscall.RLIMIT_FSIZE, Cur: uint64(config.FileSize), Max: uint64(config.FileSize)})
+	}
+	if !cgroup && config.Pids > 0 {
+		// 按用户计数，同时运行的沙箱使用同一个用户时共用该限制
+		limits = append(limits, rlimit{Resource: rlimitNproc, Cur: uint64(config.Pids), Max: uint64(config.Pids)})
+	}
+	if config.Memory > 0 {
+		// 栈的大小只受内存限制
+		limits = append(limits, rlimit{Resource: syscall.RLIMIT_STACK, Cur: uint64(config.Memory), Max: uint64(config.Memory)})
+	}
+	return limits
+}
+
+// 沙箱的init进程
+func sandboxInit() {
+	configFile, statusFile := os.NewFile(3, "config"), os.NewFile(4, "status")
+	// 避免程序继承配置和状态的管道 及 cgroup目录
+	syscall.CloseOnExec(3)
+	syscall.CloseOnExec(4)
+	syscall.CloseOnExec(5)
+
+	var status initStatus
+	var config initConfig
+	if err := json.NewDecoder(configFile).Decode(&config); err != nil {
+		status.Error = fmt.Sprintf("read config: %v", err)
+	} else if err = runInit(config, &status); err != nil {
+		status.Error = err.Error()
+	}
+	json.NewEncoder(statusFile).Encode(status)
+	os.Exit(0)
+}
+
+func runInit(config initConfig, status *initStatus) error {
+	if len(config.Args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	if err := setupRootfs(config); err != nil {
+		return fmt.Errorf("setup rootfs: %v", err)
+	}
+	if err := syscall.Sethostname([]byte("sandbox")); err != nil {
+		return fmt.Errorf("set hostname: %v", err)
+	}
+	for _, r := range config.Rlimits {
+		if err := syscall.Setrlimit(r.Resource, &syscall.Rlimit{Cur: r.Cur, Max: r.Max}); err != nil {
+			return fmt.Errorf("set rlimit %v: %v", r.Resource, err)
+		}
+	}
+
+	os.Setenv("PATH", sandboxPath)
+	path, err := exec.LookPath(config.Args[0])
+	if err != nil {
+		return err
+	}
+	cmd := &exec.Cmd{
+		Path:   path,
+		Args:   config.Args,
+		Env:    append([]string{"PATH=" + sandboxPath}, config.Env...),
+		Dir:    "/sandbox",
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		SysProcAttr: &syscall.SysProcAttr{
+			Credential: &syscall.Credential{Uid: config.Uid, Gid: config.Gid},
+			Pdeathsig:  syscall.SIGKILL,
+		},
+	}
+	// 过滤器只加载到当前线程，由该线程创建的程序继承
+	if config.Seccomp != nil {
+		runtime.LockOSThread()
+		if err = config.Seccomp.Apply(); err != nil {
+			return err
+		}
+	}
+	// 加入cgroup后启动的程序继承该cgroup，init 之后只等待程序结束
+	if config.Cgroup {
+		if err = joinCgroup(5, config.Pids); err != nil {
+			return fmt.Errorf("join cgroup: %v", err)
+		}
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	cmd.Wait()
+
+	ws := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if ws.Signaled() {
+		status.Signal = int(ws.Signal())
+	} else {
+		status.ExitCode = ws.ExitStatus()
+	}
+	if ru, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+		status.CpuTime = time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+		status.MaxRSS = ru.Maxrss << 10
+	}
+	return nil
+}
+
+// 通过cgroup目录的fd把init的所有线程加入cgroup
+// pids.current 包含init自身的线程，pids大于0时pids.max设置为 init的线程数+pids，程序可用的数量不受init影响；
+// init 之后只等待程序结束，不再创建线程
+func joinCgroup(dirfd int, pids int64) error {
+	if err := writeCgroupFile(dirfd, "cgroup.procs", "0"); err != nil {
+		return err
+	}
+	if pids <= 0 {
+		return nil
+	}
+	current, err := readCgroupFile(dirfd, "pids.current")
+	if err != nil {
+		return err
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(current), 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse pids.current: %v", err)
+	}
+	return writeCgroupFile(dirfd, "pids.max", strconv.FormatInt(n+pids, 10))
+}
+
+// 沙箱中没有挂载cgroup文件系统，通过目录的fd 访问cgroup下的文件
+func writeCgroupFile(dirfd int, name, value string) error {
+	fd, err := syscall.Openat(dirfd, name, syscall.O_WRONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("open %v: %v", name, err)
+	}
+	defer syscall.Close(fd)
+	if _, err = syscall.Write(fd, []byte(value)); err != nil {
+		return fmt.Errorf("write %v: %v", name, err)
+	}
+	return nil
+}
+
+func readCgroupFile(dirfd int, name string) (string, error) {
+	fd, err := syscall.Openat(dirfd, name, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return "", fmt.Errorf("open %v: %v", name, err)
+	}
+	defer syscall.Close(fd)
+	buf := make([]byte, 64)
+	n, err := syscall.Read(fd, buf)
+	if err != nil {
+		return "", fmt.Errorf("read %v: %v", name, err)
+	}
+	return string(buf[:n]), nil
+}
+
+// 以rootfs为根目录：rootfs只读，/sandbox 为tmpfs，挂载点在其中创建
+func setupRootfs(config initConfig) error {
+	root := config.Rootfs
+	// 挂载的变化不传播到宿主机
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return err
+	}
+	// pivot_root 要求新的根目录是挂载点
+	if err := syscall.Mount(root, root, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return err
+	}
+
+	sandboxDir := filepath.Join(root, "sandbox")
+	if err := syscall.Mount("tmpfs", sandboxDir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "size=1m,mode=755"); err != nil {
+		return err
+	}
+	for _, m := range config.Mounts {
+		if err := bindMount(m.Source, filepath.Join(sandboxDir, m.Target), !m.Writable); err != nil {
+			return fmt.Errorf("mount %v: %v", m.Source, err)
+		}
+	}
+	for _, dev := range sandboxDevices {
+		if err := syscall.Mount("/dev/"+dev, filepath.Join(root, "dev", dev), "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("mount /dev/%v: %v", dev, err)
+		}
+	}
+	if config.Tmpfs > 0 {
+		if err := syscall.Mount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV,
+			fmt.Sprintf("size=%d,mode=1777", config.Tmpfs)); err != nil {
+			return err
+		}
+	}
+	if err := syscall.Mount("proc", filepath.Join(root, "proc"), "proc",
+		syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return err
+	}
+
+	oldRoot := filepath.Join(sandboxDir, ".old")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %v", err)
+	}
+	if err := syscall.Chdir("/"); err != nil {
+		return err
+	}
+	if err := syscall.Unmount("/sandbox/.old", syscall.MNT_DETACH); err != nil {
+		return err
+	}
+	if err := os.Remove("/sandbox/.old"); err != nil {
+		return err
+	}
+	return syscall.Mount("", "/", "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, "")
+}
+
+// 创建挂载点并绑定挂载，只读挂载需要重新挂载一次
+func bindMount(source, target string, readOnly bool) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+		err = ioutil.WriteFile(target, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return err
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_NOSUID | syscall.MS_NODEV)
+	if readOnly {
+		flags |= syscall.MS_RDONLY
+	}
+	return syscall.Mount("", target, "", flags, "")
+}
+
+// 在rootfs中创建沙箱需要的挂载点
+func prepareRootfs(rootfs string) error {
+	for _, dir := range []string{"sandbox", "proc", "tmp", "dev"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0755); err != nil {
+			return err
+		}
+	}
+	for _, dev := range sandboxDevices {
+		name := filepath.Join(rootfs, "dev", dev)
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			if err = ioutil.WriteFile(name, nil, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 创建cgroup根目录，并为子cgroup启用cpu、memory、pids控制器
+// cgroup v2 中只有父cgroup的 cgroup.subtree_control 启用了控制器，子cgroup才能使用
+func setupCgroup(root string) error {
+	parent := filepath.Dir(root)
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("cgroup v2 is required: %v", err)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	for _, dir := range []string{parent, root} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory +pids"), 0644); err != nil {
+			return fmt.Errorf("enable controllers in %v: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// 一次运行使用的cgroup，为nil时不使用cgroup
+type cgroup struct {
+	path string
+}
+
+func (s *sandbox) newCgroup(config sandboxConfig) (*cgroup, error) {
+	if s.cgroupRoot == "" {
+		return nil, nil
+	}
+
+	cg := &cgroup{path: filepath.Join(s.cgroupRoot, fmt.Sprintf("%d_%d", os.Getpid(), atomic.AddInt64(&cgroupSeq, 1)))}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, err
+	}
+
+	files := map[string]string{}
+	if config.Memory > 0 {
+		files["memory.max"] = strconv.FormatInt(config.Memory, 10)
+		files["memory.swap.max"] = "0"
+	}
+	// pids.max 由init加入cgroup后按自身的线程数设置
+	if config.CpuQuota > 0 && config.CpuPeriod > 0 {
+		files["cpu.max"] = fmt.Sprintf("%d %d", config.CpuQuota, config.CpuPeriod)
+	}
+	for name, value := range files {
+		// 没有开启swap时不存在 memory.swap.max
+		if err := cg.write(name, value); err != nil && !(name == "memory.swap.max" && os.IsNotExist(err)) {
+			cg.remove()
+			return nil, err
+		}
+	}
+	return cg, nil
+}
+
+func (c *cgroup) write(name, value string) error {
+	return ioutil.WriteFile(filepath.Join(c.path, name), []byte(value), 0644)
+}
+
+// 打开cgroup目录，交给init在启动程序前加入cgroup并设置pids.max
+func (c *cgroup) open() (*os.File, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return os.Open(c.path)
+}
+
+// 读取CPU时间、峰值内存和内存事件，内核5.19以下没有memory.peak，使用rusage中的峰值内存
+func (c *cgroup) stat(res *sandboxResult) {
+	if c == nil {
+		return
+	}
+	if v, ok := c.readKey("cpu.stat", "usage_usec"); ok {
+		res.CpuTime = time.Duration(v) * time.Microsecond
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(c.path, "memory.peak")); err == nil {
+		if v, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64); err == nil {
+			res.Memory = v
+		}
+	}
+	res.OOMKill, _ = c.readKey("memory.events", "oom_kill")
+	res.LimitHit, _ = c.readKey("memory.events", "max")
+}
+
+// 读取 "key value" 格式的文件中key对应的值
+func (c *cgroup) readKey(name, key string) (int64, bool) {
+	b, err := ioutil.ReadFile(filepath.Join(c.path, name))
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			v, err := strconv.ParseInt(fields[1], 10, 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+// 沙箱内的进程被杀死后需要一段时间才会离开cgroup，删除失败时重试
+func (c *cgroup) remove() {
+	if c == nil {
+		return
+	}
+	var err error
+	for i := 0; i < 100; i++ {
+		if err = os.Remove(c.path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	log.Println("remove cgroup:", c.path, err)
+}
@@ -0,0 +1,51 @@
+// +build linux
+
+package native_executor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// init 加入cgroup后，pids.max 为自身的线程数加上程序的限制
+func TestJoinCgroup(t *testing.T) {
+	var tests = []struct {
+		pids    int64
+		current string
+		max     string // 为空时不设置pids.max
+	}{
+		{1, "5\n", "6"},
+		{16, "7\n", "23"},
+		{0, "5\n", ""},
+	}
+
+	for _, test := range tests {
+		dir, err := ioutil.TempDir("", "cgroup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		for name, value := range map[string]string{"cgroup.procs": "", "pids.current": test.current, "pids.max": ""} {
+			if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		f, err := os.Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = joinCgroup(int(f.Fd()), test.pids)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		procs, _ := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs"))
+		max, _ := ioutil.ReadFile(filepath.Join(dir, "pids.max"))
+		if string(procs) != "0" || string(max) != test.max {
+			t.Errorf("joinCgroup(%v) with pids.current %q: cgroup.procs %q, pids.max %q, want %q", test.pids, test.current, procs, max, test.max)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package native_executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"tgoj/judger/errors"
+	"tgoj/judger/seccomp"
+	"time"
+)
+
+// 在沙箱中运行的测试程序，使用测试程序本身作为可执行文件
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("TGOJ_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+
+	switch args[1] {
+	case "echo":
+		io.Copy(os.Stdout, os.Stdin)
+	case "env":
+		hostname, _ := os.Hostname()
+		wd, _ := os.Getwd()
+		fmt.Println(os.Getuid(), hostname, wd)
+	case "write":
+		fmt.Println(ioutil.WriteFile("/sandbox/exe", nil, 0644) != nil, ioutil.WriteFile("/tmp/a", nil, 0644) == nil)
+	case "loop":
+		for {
+		}
+	case "exit":
+		os.Exit(2)
+	case "socket":
+		fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+		fmt.Println(fd >= 0, err == nil)
+	case "unshare":
+		err := syscall.Unshare(syscall.CLONE_NEWUSER)
+		fmt.Println(err == nil)
+	case "clone":
+		// 与fork相同，子进程立即退出
+		pid, _, errno := syscall.RawSyscall(syscall.SYS_CLONE, uintptr(syscall.CLONE_NEWUSER|syscall.SIGCHLD), 0, 0)
+		if pid == 0 && errno == 0 {
+			syscall.RawSyscall(syscall.SYS_EXIT_GROUP, 0, 0, 0)
+		}
+		fmt.Println(errno == 0)
+	case "clone3":
+		// clone3 的系统调用号在amd64、arm64上都为435，参数为空时内核返回EINVAL
+		_, _, errno := syscall.RawSyscall(435, 0, 0, 0)
+		fmt.Println(errno == syscall.ENOSYS)
+	case "cgroup":
+		b, _ := ioutil.ReadFile("/proc/self/cgroup")
+		fmt.Print(string(b))
+	}
+	os.Exit(0)
+}
+
+func TestSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Getuid() != 0 {
+		t.Skip("native sandbox requires root on linux")
+	}
+
+	dir, err := ioutil.TempDir("", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rootfs := filepath.Join(dir, "rootfs")
+	if err = prepareRootfs(rootfs); err != nil {
+		t.Fatal(err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		mode    string
+		seccomp *seccomp.Profile
+		stdin   string
+		stdout  string
+		verdict errors.JudgerError
+	}{
+		{"echo", nil, "1 2\n", "1 2\n", errors.AC},
+		{"env", nil, "", fmt.Sprintf("%v sandbox /sandbox\n", sandboxUid), errors.AC},
+		{"write", nil, "", "true true\n", errors.AC},
+		{"loop", nil, "", "", errors.TLE},
+		{"exit", nil, "", "", errors.RE},
+		{"socket", nil, "", "true true\n", errors.AC},
+		{"echo", seccomp.New(), "1 2\n", "1 2\n", errors.AC},
+		{"socket", seccomp.New(), "", "", errors.RF},
+		{"socket", seccomp.New("socket"), "", "true true\n", errors.AC},
+		// 不能创建命名空间，clone3 返回ENOSYS
+		{"unshare", seccomp.New(), "", "", errors.RF},
+		{"clone", seccomp.New(), "", "", errors.RF},
+		{"clone3", seccomp.New(), "", "true\n", errors.AC},
+	}
+
+	s := &sandbox{}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		res, err := s.run(sandboxConfig{
+			Rootfs:  rootfs,
+			Args:    []string{"/sandbox/exe", "-test.run=TestHelperProcess", "--", test.mode},
+			Env:     []string{"TGOJ_HELPER=1"},
+			Mounts:  []mount{{Source: self, Target: "exe"}},
+			Tmpfs:   1 << 20,
+			Timeout: time.Second,
+			Seccomp: test.seccomp,
+			Stdin:   strings.NewReader(test.stdin),
+			Stdout:  &stdout,
+			Stderr:  &stderr,
+		})
+		if err != nil {
+			t.Fatalf("%v: %v", test.mode, err)
+		}
+		if err = runError(res, stderr.String()); errors.Code(err) != test.verdict || stdout.String() != test.stdout {
+			t.Errorf("%v: %q, %v, %+v", test.mode, stdout.String(), err, res)
+		}
+	}
+}
+
+// 程序运行在单独的cgroup中，需要cgroup v2
+func TestSandbox_Cgroup(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Getuid() != 0 {
+		t.Skip("native sandbox requires root on linux")
+	}
+	if err := setupCgroup(CgroupRoot); err != nil {
+		t.Skip(err)
+	}
+
+	dir, err := ioutil.TempDir("", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rootfs := filepath.Join(dir, "rootfs")
+	if err = prepareRootfs(rootfs); err != nil {
+		t.Fatal(err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	s := &sandbox{cgroupRoot: CgroupRoot}
+	res, err := s.run(sandboxConfig{
+		Rootfs:  rootfs,
+		Args:    []string{"/sandbox/exe", "-test.run=TestHelperProcess", "--", "cgroup"},
+		Env:     []string{"TGOJ_HELPER=1"},
+		Mounts:  []mount{{Source: self, Target: "exe"}},
+		Memory:  64 << 20,
+		Pids:    16, // 程序是Go的测试程序，需要多个线程
+		Timeout: time.Second,
+		Stdout:  &stdout,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只有程序所在的cgroup，init 占用的内存不计入
+	cg := fmt.Sprintf("/%s/%d_", filepath.Base(CgroupRoot), os.Getpid())
+	if !strings.Contains(stdout.String(), cg) || res.Memory <= 0 || res.Memory >= 64<<20 {
+		t.Errorf("cgroup %q, want %v*, %+v", stdout.String(), cg, res)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	var tests = []struct {
+		res     sandboxResult
+		verdict errors.JudgerError
+	}{
+		{sandboxResult{}, errors.AC},
+		{sandboxResult{TimedOut: true, Signal: 9}, errors.TLE},
+		{sandboxResult{CpuLimitExceeded: true, Signal: 24}, errors.TLE},
+		{sandboxResult{Signal: 9, OOMKill: 1}, errors.MLE},
+		{sandboxResult{Signal: 9, LimitHit: 3}, errors.MLE},
+		{sandboxResult{ExitCode: 2, LimitHit: 3}, errors.RE},
+		{sandboxResult{Signal: 11, LimitHit: 1}, errors.RE},
+		{sandboxResult{FileSizeExceeded: true, Signal: 25}, errors.OLE},
+		{sandboxResult{Signal: 11}, errors.RE},
+		{sandboxResult{SyscallBlocked: true, Signal: 31}, errors.RF},
+		{sandboxResult{ExitCode: 2}, errors.RE},
+		{sandboxResult{ExitCode: 1}, errors.UNKNOWN},
+	}
+
+	for _, test := range tests {
+		if err := runError(test.res, ""); errors.Code(err) != test.verdict {
+			t.Errorf("runError(%+v) = %v", test.res, err)
+		}
+	}
+}
+
+func TestParseUser(t *testing.T) {
+	var tests = []struct {
+		user     string
+		uid, gid uint32
+		ok       bool
+	}{
+		{"", sandboxUid, sandboxGid, true},
+		{"1000:1001", 1000, 1001, true},
+		{"nobody", 0, 0, false},
+		{"1000:abc", 0, 0, false},
+	}
+
+	for _, test := range tests {
+		uid, gid, err := parseUser(test.user)
+		if (err == nil) != test.ok || uid != test.uid || gid != test.gid {
+			t.Errorf("parseUser(%q) = %v, %v, %v", test.user, uid, gid, err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// +build !linux
+
+package native_executor
+
+import "tgoj/judger/errors"
+
+type sandbox struct {
+	cgroupRoot string
+}
+
+func setupCgroup(root string) error {
+	return errors.New(errors.ENV, "native sandbox requires linux")
+}
+
+func prepareRootfs(rootfs string) error {
+	return errors.New(errors.ENV, "native sandbox requires linux")
+}
+
+func (s *sandbox) run(config sandboxConfig) (sandboxResult, error) {
+	return sandboxResult{}, errors.New(errors.ENV, "native sandbox requires linux")
+}
@@ -0,0 +1,94 @@
+package native_executor
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/executor"
+	"tgoj/judger/utils"
+)
+
+// 流式校验的结果
+type streamResult struct {
+	passed int
+	err    error
+}
+
+// 运行第i个用例并同时校验，程序的stdout直接交给校验器，不写入输出文件
+// 校验器发现第一个不同时立即结束沙箱；校验器给出错误时结果为该错误，校验通过时才按程序的运行结果判定
+func (n *NativeExecutor) RunStream(task executor.RunTask, i int, caseRes *judger.CaseResult) error {
+	if err := n.checkTask(task); err != nil {
+		return err
+	}
+	stdin, err := inputReader(task.TestCases[i].InputPath)
+	if err != nil {
+		return err
+	}
+	answer := fmt.Sprintf("%s/answer/%s", ResourcePath, task.TestCases[i].AnswerPath)
+
+	kill := make(chan struct{})
+	pr, pw := io.Pipe()
+	resultCh := make(chan streamResult, 1)
+	go func() {
+		passed, err := task.StreamVerifier.VerifyStream(pr, answer)
+		if err != nil {
+			close(kill)
+		}
+		resultCh <- streamResult{passed, err}
+		// 继续读取剩余的输出，避免程序阻塞在写stdout上
+		io.Copy(ioutil.Discard, pr)
+	}()
+
+	outputLimit := task.MaxOutput()
+	stdout := &countWriter{w: pw, limit: outputLimit + 1}
+	stderr := utils.NewHeadTailBuffer(stderrLimit, stderrLimit)
+	config := n.runConfig(task, stdin, stdout, stderr)
+	config.Kill = kill
+	res, err := n.sandbox.run(config)
+	pw.Close()
+	verified := <-resultCh
+
+	caseRes.WallTime = res.WallTime
+	if err != nil {
+		log.Println(task.ID, err)
+		return err
+	}
+
+	// 被提前结束时没有统计信息
+	if !res.Killed {
+		caseRes.CpuTime, caseRes.Memory = res.CpuTime, res.Memory
+	}
+	// 校验器给出错误时以校验结果为准：发现不同时程序可能已经在退出，是否被结束、退出码都不确定
+	if verified.err != nil {
+		return executor.VerifyResult(task.Task, i, caseRes, verified.passed, verified.err)
+	}
+
+	if stdout.n > outputLimit {
+		return errors.New(errors.OLE, fmt.Sprintf("output exceeds %v bytes", outputLimit))
+	}
+	if err = runError(res, stderr.String()); err != nil {
+		return err
+	}
+	return executor.VerifyResult(task.Task, i, caseRes, verified.passed, nil)
+}
+
+// 记录写入的字节数，超过limit后不再写入，程序继续写stdout时会收到SIGPIPE
+type countWriter struct {
+	w     io.Writer
+	n     int64
+	limit int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	if left := c.limit - c.n; int64(len(p)) > left {
+		n, _ := c.w.Write(p[:left])
+		c.n += int64(n)
+		return n, io.ErrShortWrite
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -60,3 +60,9 @@ func WithVerifyConcurrency(n int) Option {
 		return executor.SetVerifyConcurrency(n)
 	}
 }
+
+func WithAutoscaler(a Autoscaler) Option {
+	return func(executor Executor) error {
+		return executor.SetAutoscaler(a)
+	}
+}
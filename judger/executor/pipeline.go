@@ -0,0 +1,437 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/language"
+	"tgoj/judger/verifier"
+)
+
+type Status int
+
+const (
+	CREATED Status = iota
+	RUNNING
+	DESTROYING // 等待所有任务结束
+	DESTROYED  // 已销毁，不能使用
+)
+
+// 每种Executor在各自的环境(docker容器、原生沙箱)中编译和运行程序，其余的阶段调度、校验由Pipeline完成
+type Backend interface {
+	// 启动编译环境
+	EnableCompiler() error
+
+	// 把 CodePath 编译为 ExePath，编译失败返回CE
+	CompileTask(task *judger.Task, lang *language.Language) error
+
+	// 解释型语言运行用例前检查语法，失败返回CE
+	SyntaxCheck(task RunTask) error
+
+	// 运行第i个用例，输出写入 OutputPath，运行时间等信息记录在res中
+	RunCase(task RunTask, i int, res *judger.CaseResult) error
+
+	// 运行第i个用例，同时通过 task.StreamVerifier 校验输出
+	RunStream(task RunTask, i int, res *judger.CaseResult) error
+
+	// 运行交互题的第i个用例，结果由interactor给出
+	RunInteractive(task RunTask, i int, res *judger.CaseResult) error
+
+	// 存放code、input、output、exe、answer等资源的目录
+	Resource() string
+
+	// special judge 在该环境中运行checker
+	verifier.Sandbox
+}
+
+// 将评测分为编译、运行、校验 三个阶段，通过channel传递任务，每个阶段由多个goroutine处理
+// 实现了 Executor 中与运行环境无关的部分，嵌入到各个Executor中
+type Pipeline struct {
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	backend    Backend
+
+	resultCh chan<- judger.Result
+	taskCh   <-chan *judger.Task
+
+	compileTaskCh compileTaskChan
+	runTaskCh     runTaskChan
+	verifyTaskCh  verifyTaskChan
+
+	verifier verifier.Verifier
+	status   Status
+
+	autoscaleCancel context.CancelFunc // 停止Autoscaler
+	autoscaleDone   chan struct{}      // Autoscaler 的goroutine退出后关闭
+}
+
+func NewPipeline(backend Backend) *Pipeline {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	return &Pipeline{
+		ctx:           ctx,
+		cancelFunc:    cancelFunc,
+		backend:       backend,
+		compileTaskCh: newCompileTaskChan(DefaultChannelSize),
+		runTaskCh:     newRunTaskChan(DefaultChannelSize),
+		verifyTaskCh:  newVerifyTaskChan(DefaultChannelSize),
+		verifier:      verifier.StandardVerifier{Normalize: verifier.DefaultNormalization},
+		status:        CREATED,
+	}
+}
+
+/****  Initialization      *****/
+func (p *Pipeline) SetVerifier(v verifier.Verifier) error {
+	p.verifier = v
+	return nil
+}
+
+func (p *Pipeline) SetResultChan(resultCh chan<- judger.Result) error {
+	p.resultCh = resultCh
+	return nil
+}
+
+func (p *Pipeline) SetTaskChan(taskCh <-chan *judger.Task) error {
+	p.taskCh = taskCh
+	return nil
+}
+
+// 调整为n个goroutine，可以在运行时增加或减少；如果没有启动编译环境，会自动启动
+func (p *Pipeline) SetCompileConcurrency(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("if set, compile concurrency must be greater than 0, but received %v", n)
+	}
+
+	if err := p.backend.EnableCompiler(); err != nil {
+		return err
+	}
+
+	p.compileTaskCh.workers.Resize(n, func(stop <-chan struct{}) {
+		p.compileTaskCh.Add(1)
+		go p.compile(stop)
+	})
+	return nil
+}
+
+func (p *Pipeline) SetRunConcurrency(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("if set, run concurrency must be greater than 0, but received %v", n)
+	}
+
+	p.runTaskCh.workers.Resize(n, func(stop <-chan struct{}) {
+		p.runTaskCh.Add(1)
+		go p.run(stop)
+	})
+	return nil
+}
+
+func (p *Pipeline) SetVerifyConcurrency(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("if set, verify concurrency must be greater than 0, but received %v", n)
+	}
+
+	p.verifyTaskCh.workers.Resize(n, func(stop <-chan struct{}) {
+		p.verifyTaskCh.Add(1)
+		go p.verify(stop)
+	})
+	return nil
+}
+
+// 各阶段当前的goroutine数量
+func (p *Pipeline) Concurrency() (compile, run, verify int) {
+	return p.compileTaskCh.workers.Len(), p.runTaskCh.workers.Len(), p.verifyTaskCh.workers.Len()
+}
+
+// 按channel中等待的任务数量自动调整各阶段的goroutine数量，Destroy时停止；只能设置一次
+func (p *Pipeline) SetAutoscaler(a Autoscaler) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	if p.autoscaleDone != nil {
+		return fmt.Errorf("autoscaler has already been set")
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.autoscaleCancel, p.autoscaleDone = cancel, make(chan struct{})
+	go func() {
+		defer close(p.autoscaleDone)
+		a.Run(ctx, p.stages()...)
+	}()
+	return nil
+}
+
+// Autoscaler 调整的各阶段
+func (p *Pipeline) stages() []Stage {
+	return []Stage{
+		{Name: "compile", Workers: &p.compileTaskCh.workers, Resize: p.SetCompileConcurrency,
+			Queued: func() int { return len(p.compileTaskCh.ch) }},
+		{Name: "run", Workers: &p.runTaskCh.workers, Resize: p.SetRunConcurrency,
+			Queued: func() int { return len(p.runTaskCh.ch) }},
+		{Name: "verify", Workers: &p.verifyTaskCh.workers, Resize: p.SetVerifyConcurrency,
+			Queued: func() int { return len(p.verifyTaskCh.ch) }},
+	}
+}
+
+/****  Operation      *****/
+func (p *Pipeline) Destroy(force bool) error {
+	// 停止所有 goroutine
+	// 如果在RUNNING状态收到退出的信息，说明是强制退出，不会处理内部还有的任务
+	// 如果在DESTROYING状态收到退出的信息，则是非强制退出，可以依次等待每个阶段残留的任务运行完成后再退出
+	//     每个阶段处理完task后，关闭发往下一个阶段的channel
+	if !force {
+		p.status = DESTROYING
+	}
+	// 先停止Autoscaler，并且不再允许调整goroutine数量，保证等待期间不会有新的goroutine
+	if p.autoscaleDone != nil {
+		p.autoscaleCancel()
+		<-p.autoscaleDone
+	}
+	p.compileTaskCh.workers.Close()
+	p.runTaskCh.workers.Close()
+	p.verifyTaskCh.workers.Close()
+	p.cancelFunc()
+
+	p.compileTaskCh.Wait()
+	close(p.runTaskCh.ch)
+
+	p.runTaskCh.Wait()
+	close(p.verifyTaskCh.ch)
+
+	p.verifyTaskCh.Wait()
+	p.status = DESTROYED
+	return nil
+}
+
+func (p *Pipeline) Execute() error {
+	p.status = RUNNING
+	for {
+		select {
+		case <-p.ctx.Done():
+			// compileTaskCh 只有一个sender，所以可以直接关闭
+			close(p.compileTaskCh.ch)
+			return nil
+		case task := <-p.taskCh: // 接收外部传入的任务，并根据任务状态执行
+			// 没有用例的任务不会有未通过的用例，不能判定为AC
+			if len(task.TestCases) == 0 {
+				p.failTask(task, errors.New(errors.ENV, "task has no test cases"))
+				continue
+			}
+			switch task.Status {
+			case judger.CREATED:
+				// 解释型语言跳过编译阶段，不支持的语言交给编译阶段返回CE
+				if lang, err := language.Get(task.Language); err == nil && lang.Interpreted() {
+					p.runTaskCh.ch <- RunTask{Task: task}
+				} else {
+					p.compileTaskCh.ch <- compileTask{Task: task}
+				}
+			case judger.COMPILED:
+				p.runTaskCh.ch <- RunTask{Task: task}
+			case judger.EXECUTED:
+				p.verifyTaskCh.ch <- verifyTask{Task: task}
+			}
+		}
+	}
+}
+
+// stop关闭时处理完当前任务后退出，用于减少goroutine数量
+func (p *Pipeline) compile(stop <-chan struct{}) {
+	defer p.compileTaskCh.Done()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.finishCompile()
+			return
+		case <-stop:
+			return
+		case task, ok := <-p.compileTaskCh.ch:
+			if !ok {
+				p.finishCompile()
+				return
+			}
+			p.compileTaskCh.workers.Do(func() { p.processCompileTask(task) })
+		}
+	}
+}
+
+func (p *Pipeline) finishCompile() {
+	if p.status == DESTROYING { // 非强制退出
+		log.Println("processing left compile task")
+		// compileTaskCh 已关闭，因为带缓冲，处理完channel内剩余task再退出
+		for task := range p.compileTaskCh.ch {
+			p.processCompileTask(task)
+		}
+	}
+}
+
+func (p *Pipeline) processCompileTask(task compileTask) {
+	lang, err := TaskLanguage(task.Task)
+	if err == nil {
+		// 可执行文件相对exe目录的路径 与 源代码文件相对code目录的路径 相同(去掉后缀)
+		task.ExePath = lang.ExePath(task.CodePath)
+		err = p.backend.CompileTask(task.Task, lang)
+	}
+	if err != nil {
+		p.failTask(task.Task, err)
+		return
+	}
+
+	task.Status = judger.COMPILED
+	p.runTaskCh.ch <- RunTask{Task: task.Task}
+}
+
+// stop关闭时处理完当前任务后退出，用于减少goroutine数量
+func (p *Pipeline) run(stop <-chan struct{}) {
+	defer p.runTaskCh.Done()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.finishRun()
+			return
+		case <-stop:
+			return
+		case task, ok := <-p.runTaskCh.ch:
+			if !ok {
+				p.finishRun()
+				return
+			}
+			p.runTaskCh.workers.Do(func() { p.processRunTask(task) })
+		}
+	}
+}
+
+func (p *Pipeline) finishRun() {
+	if p.status == DESTROYING { // 非强制退出
+		log.Println("processing left run task")
+		// runTaskCh 已关闭，因为带缓冲，处理完channel内剩余task再退出
+		for task := range p.runTaskCh.ch {
+			p.processRunTask(task)
+		}
+	}
+}
+
+func (p *Pipeline) processRunTask(task RunTask) {
+	var err error
+	task.Lang, err = TaskLanguage(task.Task)
+	if err == nil && task.Lang.Interpreted() {
+		err = p.backend.SyntaxCheck(task)
+	}
+	if err != nil {
+		p.failTask(task.Task, err)
+		return
+	}
+
+	// 每个用例单独运行，某个用例出错不影响后续用例
+	// FailFast 模式下每个用例运行后立即校验，出现未通过的用例后跳过剩余用例
+	// 交互题的结果由interactor给出，不需要校验
+	// 流式校验时在运行的同时完成校验
+	task.StreamVerifier = p.streamVerifier(task.Task)
+	verified := task.Interactor != "" || task.StreamVerifier != nil
+	cases := make([]judger.CaseResult, len(task.TestCases))
+	for i := range task.TestCases {
+		var err error
+		switch {
+		case task.Interactor != "":
+			err = p.backend.RunInteractive(task, i, &cases[i])
+		case task.StreamVerifier != nil:
+			err = p.backend.RunStream(task, i, &cases[i])
+		default:
+			err = p.backend.RunCase(task, i, &cases[i])
+		}
+		cases[i].Verdict, cases[i].Score, cases[i].Error = verifier.Verdict(err), verifier.Score(err), err
+
+		if task.FailFast {
+			if err == nil && !verified {
+				p.verifyCase(task.Task, i, &cases[i])
+			}
+			if cases[i].Verdict != errors.AC {
+				skipCases(cases[i+1:])
+				break
+			}
+		}
+	}
+
+	task.Task.Status = judger.EXECUTED
+	p.verifyTaskCh.ch <- verifyTask{Task: task.Task, cases: cases, verified: task.FailFast || verified}
+}
+
+// stop关闭时处理完当前任务后退出，用于减少goroutine数量
+func (p *Pipeline) verify(stop <-chan struct{}) {
+	defer p.verifyTaskCh.Done()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			p.finishVerify()
+			return
+		case <-stop:
+			return
+		case task, ok := <-p.verifyTaskCh.ch:
+			if !ok {
+				p.finishVerify()
+				return
+			}
+			p.verifyTaskCh.workers.Do(func() { p.processVerifyTask(task) })
+		}
+	}
+}
+
+func (p *Pipeline) finishVerify() {
+	if p.status == DESTROYING { // 非强制退出
+		log.Println("processing left verify task")
+		// verifyTaskCh 已关闭，因为带缓冲，处理完channel内剩余task再退出
+		for task := range p.verifyTaskCh.ch {
+			p.processVerifyTask(task)
+		}
+	}
+}
+
+func (p *Pipeline) processVerifyTask(task verifyTask) {
+	// 外部传入的EXECUTED任务没有运行阶段的结果
+	if task.cases == nil {
+		task.cases = make([]judger.CaseResult, len(task.TestCases))
+	}
+
+	// 运行阶段出错的用例不再校验
+	if !task.verified {
+		for i := range task.TestCases {
+			if task.cases[i].Verdict == errors.AC {
+				p.verifyCase(task.Task, i, &task.cases[i])
+			}
+			if task.FailFast && task.cases[i].Verdict != errors.AC {
+				skipCases(task.cases[i+1:])
+				break
+			}
+		}
+	}
+
+	p.resultCh <- judger.NewResult(task.Task, task.cases)
+}
+
+func skipCases(cases []judger.CaseResult) {
+	for i := range cases {
+		cases[i] = judger.CaseResult{Verdict: errors.SKIPPED}
+	}
+}
+
+// 任务使用的语言，不支持的语言视为CE
+func TaskLanguage(task *judger.Task) (*language.Language, error) {
+	lang, err := language.Get(task.Language)
+	if err != nil {
+		return nil, errors.New(errors.CE, err.Error())
+	}
+	return lang, nil
+}
+
+// 任务无法继续评测，直接返回结果
+func (p *Pipeline) failTask(task *judger.Task, err error) {
+	p.resultCh <- judger.Result{
+		ID:       task.ID,
+		Success:  false,
+		Verdict:  errors.Code(err),
+		MaxScore: task.MaxScore(),
+		Error:    err,
+	}
+}
@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"testing"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"time"
+)
+
+// Destroy 等待Autoscaler退出，之后不能再调整goroutine数量
+func TestPipeline_Autoscaler(t *testing.T) {
+	p := NewPipeline(nil)
+	if err := p.SetRunConcurrency(1); err != nil {
+		t.Fatal(err)
+	}
+	a := Autoscaler{Max: 4, Interval: time.Millisecond}
+	if err := p.SetAutoscaler(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetAutoscaler(a); err == nil {
+		t.Error("SetAutoscaler twice should fail")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := p.Destroy(true); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-p.autoscaleDone:
+	default:
+		t.Error("autoscaler is still running after Destroy")
+	}
+	if err := p.SetRunConcurrency(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, run, _ := p.Concurrency(); run != 1 {
+		t.Errorf("run workers = %v after Destroy, want 1", run)
+	}
+}
+
+// 没有用例的任务判定为ENV，不进入编译阶段
+func TestPipeline_NoTestCases(t *testing.T) {
+	taskCh, resultCh := make(chan *judger.Task), make(chan judger.Result, 1)
+	p := NewPipeline(nil)
+	p.SetTaskChan(taskCh)
+	p.SetResultChan(resultCh)
+	go p.Execute()
+
+	taskCh <- &judger.Task{ID: 1, CodePath: "success.go", Status: judger.CREATED}
+	if res := <-resultCh; res.ID != 1 || res.Success || res.Verdict != errors.ENV {
+		t.Errorf("result = %v, want ENV", res)
+	}
+	if err := p.Destroy(true); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"sync"
+	"tgoj/judger"
+	"tgoj/judger/language"
+	"tgoj/judger/verifier"
+)
+
+const (
+	DefaultChannelSize = 100
+	DefaultOutputLimit = 16 << 20 // 16MB
+)
+
+type compileTask struct {
+	*judger.Task
+}
+
+// 可能有多个goroutine 同时写channel，在调用Destroy 非强制结束的时候，需要等到最后一个goroutine处理完之后才退出，因此加入wait group
+// 调用Destroy时，当compile goroutine都结束之后，关闭runTask channel，因为对于这个channel，已经没用sender了。下面的runTaskChan也类似
+type compileTaskChan struct {
+	sync.WaitGroup
+	ch      chan compileTask
+	workers Workers // 监听ch的goroutine
+}
+
+func newCompileTaskChan(size int) compileTaskChan {
+	return compileTaskChan{
+		ch: make(chan compileTask, size),
+	}
+}
+
+// 运行阶段的任务，交给 Backend 运行每个用例
+type RunTask struct {
+	*judger.Task
+	Lang           *language.Language
+	StreamVerifier verifier.StreamVerifier // 流式校验使用的校验器，为nil时不使用流式校验
+}
+
+// 每个用例输出的大小限制，Task.OutputLimit 为0时使用 DefaultOutputLimit
+func (t RunTask) MaxOutput() int64 {
+	if t.OutputLimit <= 0 {
+		return DefaultOutputLimit
+	}
+	return t.OutputLimit
+}
+
+type runTaskChan struct {
+	sync.WaitGroup
+	ch      chan RunTask
+	workers Workers // 监听ch的goroutine
+}
+
+func newRunTaskChan(size int) runTaskChan {
+	return runTaskChan{
+		ch: make(chan RunTask, size),
+	}
+}
+
+type verifyTask struct {
+	*judger.Task
+	cases    []judger.CaseResult // 每个用例运行阶段的结果，为AC的用例才需要校验
+	verified bool                // 已在运行阶段完成校验
+}
+
+// 同runTaskChan
+type verifyTaskChan struct {
+	sync.WaitGroup
+	ch      chan verifyTask
+	workers Workers // 监听ch的goroutine
+}
+
+func newVerifyTaskChan(size int) verifyTaskChan {
+	return verifyTaskChan{
+		ch: make(chan verifyTask, size),
+	}
+}
@@ -0,0 +1,84 @@
+package executor
+
+import (
+	"fmt"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/verifier"
+)
+
+// 资源目录下的路径，dir 为 input、output、answer 等
+func resourcePath(resource, dir, path string) string {
+	return fmt.Sprintf("%s/%s/%s", resource, dir, path)
+}
+
+// 校验第i个用例的输出，结果记录在res中
+func (p *Pipeline) verifyCase(task *judger.Task, i int, res *judger.CaseResult) {
+	tc := task.TestCases[i]
+	resource := p.backend.Resource()
+	passed, err := p.VerifyFiles(task, resourcePath(resource, "input", tc.InputPath),
+		resourcePath(resource, "output", tc.OutputPath), resourcePath(resource, "answer", tc.AnswerPath))
+	err = VerifyResult(task, i, res, passed, err)
+	res.Score, res.Verdict, res.Error = verifier.Score(err), verifier.Verdict(err), err
+}
+
+// 使用任务的校验器校验输出，参数为宿主机上的文件路径
+func (p *Pipeline) VerifyFiles(task *judger.Task, input, output, answer string) (int, error) {
+	v, err := p.TaskVerifier(task)
+	if err != nil {
+		return 0, errors.New(errors.ENV, err.Error())
+	}
+	if iv, ok := v.(verifier.InputVerifier); ok {
+		return iv.VerifyInput(input, output, answer)
+	}
+	return v.Verify(output, answer)
+}
+
+// 在res中记录校验通过的数量 及 输出与答案的差异，返回隐藏差异后的错误
+func VerifyResult(task *judger.Task, i int, res *judger.CaseResult, passed int, err error) error {
+	res.Passed = passed
+	// 隐藏所有WA的信息，包括无序比较、checker 等在错误信息中给出的差异
+	if task.HideMismatch && verifier.Verdict(err) == errors.WA {
+		return errors.New(errors.WA, "wrong answer")
+	}
+	if m, ok := err.(*verifier.Mismatch); ok {
+		m.Case = i
+		res.Mismatch = m
+	}
+	return err
+}
+
+// 任务使用的校验器，依次为 Task.Verifier、Task.VerifierName 对应的校验器、Executor的校验器
+// special judge 使用Backend运行checker
+func (p *Pipeline) TaskVerifier(task *judger.Task) (verifier.Verifier, error) {
+	v := p.verifier
+	switch {
+	case task.Verifier != nil:
+		v = task.Verifier
+	case task.VerifierName != "":
+		var err error
+		if v, err = verifier.New(task.VerifierName, task.VerifierOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	if c, ok := v.(verifier.CheckerVerifier); ok && c.Sandbox == nil {
+		c.Sandbox = p.backend
+		v = c
+	}
+	return v, nil
+}
+
+// 任务使用流式校验时返回支持流式校验的校验器，否则返回nil
+func (p *Pipeline) streamVerifier(task *judger.Task) verifier.StreamVerifier {
+	if !task.Stream || task.Interactor != "" {
+		return nil
+	}
+	// 无法创建校验器时不使用流式校验，由校验阶段给出错误
+	v, err := p.TaskVerifier(task)
+	if err != nil {
+		return nil
+	}
+	sv, _ := v.(verifier.StreamVerifier)
+	return sv
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tgoj/judger"
+	"tgoj/judger/errors"
+	"tgoj/judger/verifier"
+)
+
+func TestVerifyResult_HideMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output, answer := filepath.Join(dir, "output.txt"), filepath.Join(dir, "answer.txt")
+	if err = ioutil.WriteFile(output, []byte("1\n3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(answer, []byte("2\n1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		v       verifier.Verifier
+		hide    bool
+		verdict errors.JudgerError
+		leak    bool // 错误信息或Mismatch中给出了差异
+	}{
+		{verifier.StandardVerifier{}, false, errors.WA, true},
+		{verifier.StandardVerifier{}, true, errors.WA, false},
+		{verifier.UnorderedVerifier{}, false, errors.WA, true},
+		{verifier.UnorderedVerifier{}, true, errors.WA, false},
+	}
+
+	for _, test := range tests {
+		var res judger.CaseResult
+		passed, err := test.v.Verify(output, answer)
+		err = VerifyResult(&judger.Task{HideMismatch: test.hide}, 0, &res, passed, err)
+		leak := res.Mismatch != nil || strings.Contains(err.Error(), "3")
+		if verifier.Verdict(err) != test.verdict || leak != test.leak {
+			t.Errorf("%T hide %v: %v, %+v", test.v, test.hide, err, res.Mismatch)
+		}
+	}
+}
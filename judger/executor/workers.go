@@ -0,0 +1,147 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultAutoscaleInterval = time.Second
+
+// 一个阶段的goroutine，支持在运行时增加和减少数量
+type Workers struct {
+	mu     sync.Mutex
+	stops  []chan struct{} // 每个goroutine的退出信号
+	busy   int32           // 正在处理任务的goroutine数量
+	closed bool            // Close之后不再调整数量
+}
+
+// 调整为n个goroutine: 增加时调用start启动新的goroutine，
+// 减少时关闭多余goroutine的退出信号，goroutine应在处理完当前任务后退出；Close之后不做任何事
+func (w *Workers) Resize(n int, start func(stop <-chan struct{})) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+
+	for len(w.stops) < n {
+		stop := make(chan struct{})
+		w.stops = append(w.stops, stop)
+		start(stop)
+	}
+	for len(w.stops) > n {
+		close(w.stops[len(w.stops)-1])
+		w.stops = w.stops[:len(w.stops)-1]
+	}
+}
+
+// 之后的Resize不再启动goroutine，Destroy在等待goroutine结束前调用
+func (w *Workers) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+}
+
+// goroutine的数量，不包含已收到退出信号的goroutine
+func (w *Workers) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.stops)
+}
+
+// 正在处理任务的goroutine数量
+func (w *Workers) Busy() int {
+	return int(atomic.LoadInt32(&w.busy))
+}
+
+// 处理一个任务，处理期间计入Busy
+func (w *Workers) Do(f func()) {
+	atomic.AddInt32(&w.busy, 1)
+	defer atomic.AddInt32(&w.busy, -1)
+	f()
+}
+
+// 由Autoscaler调整数量的一个阶段
+type Stage struct {
+	Name    string
+	Workers *Workers
+	Queued  func() int        // channel中等待的任务数量
+	Resize  func(n int) error // 调整goroutine数量，即 SetXXXConcurrency
+}
+
+// 按每个阶段channel中等待的任务数量自动调整goroutine数量
+type Autoscaler struct {
+	Min            int           // 每个阶段最少的goroutine数量，为0时视为1
+	Max            int           // 每个阶段最多的goroutine数量
+	TasksPerWorker int           // 每增加一个goroutine对应的等待任务数量，为0时视为1
+	Interval       time.Duration // 检查间隔，为0时使用 DefaultAutoscaleInterval
+}
+
+func (a Autoscaler) Validate() error {
+	if a.Max <= 0 || a.Max < a.Min {
+		return fmt.Errorf("autoscaler max must be greater than 0 and not less than min, but received min %v, max %v", a.Min, a.Max)
+	}
+	return nil
+}
+
+// 目标数量为 忙碌的goroutine + 处理等待任务需要的goroutine，限制在[Min, Max]之间
+// 增加时直接调整到目标数量，减少时每次只减少一个，避免任务陆续到达时反复启动、退出
+func (a Autoscaler) Target(current, busy, queued int) int {
+	min, perWorker := a.Min, a.TasksPerWorker
+	if min <= 0 {
+		min = 1
+	}
+	if perWorker <= 0 {
+		perWorker = 1
+	}
+
+	target := busy + (queued+perWorker-1)/perWorker
+	if target < current {
+		target = current - 1
+	}
+	if target > a.Max {
+		target = a.Max
+	}
+	if target < min {
+		target = min
+	}
+	return target
+}
+
+// 每隔Interval调整一次各阶段的goroutine数量，直到ctx结束
+func (a Autoscaler) Run(ctx context.Context, stages ...Stage) {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = DefaultAutoscaleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, s := range stages {
+				// 未设置并发数的阶段(例如没有启用编译)不调整；Destroy后不再启动goroutine
+				current := s.Workers.Len()
+				if current == 0 || ctx.Err() != nil {
+					continue
+				}
+				target := a.Target(current, s.Workers.Busy(), s.Queued())
+				if target == current {
+					continue
+				}
+				if err := s.Resize(target); err != nil {
+					log.Printf("autoscale %v: %v", s.Name, err)
+					continue
+				}
+				log.Printf("autoscale %v: %v -> %v", s.Name, current, target)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWorkers_Resize(t *testing.T) {
+	var w Workers
+	var wg sync.WaitGroup
+	tasks := make(chan int)
+	exited := make(chan struct{}, 10)
+	start := func(stop <-chan struct{}) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					exited <- struct{}{}
+					return
+				case <-tasks:
+					w.Do(func() {})
+				}
+			}
+		}()
+	}
+
+	w.Resize(3, start)
+	if w.Len() != 3 {
+		t.Fatalf("Len() = %v, want 3", w.Len())
+	}
+	for i := 0; i < 10; i++ {
+		tasks <- i
+	}
+
+	// 减少的goroutine收到退出信号
+	w.Resize(1, start)
+	<-exited
+	<-exited
+	if w.Len() != 1 {
+		t.Fatalf("Len() = %v, want 1", w.Len())
+	}
+	tasks <- 0
+
+	w.Resize(2, start)
+	if w.Len() != 2 {
+		t.Fatalf("Len() = %v, want 2", w.Len())
+	}
+	w.Resize(0, start)
+	wg.Wait()
+	if w.Busy() != 0 {
+		t.Errorf("Busy() = %v, want 0", w.Busy())
+	}
+
+	// Close之后不再启动goroutine
+	w.Close()
+	w.Resize(2, start)
+	if w.Len() != 0 {
+		t.Errorf("Len() = %v after Close, want 0", w.Len())
+	}
+}
+
+func TestAutoscaler_Target(t *testing.T) {
+	var tests = []struct {
+		a                     Autoscaler
+		current, busy, queued int
+		target                int
+	}{
+		{Autoscaler{Max: 8}, 1, 1, 3, 4},                    // 增加到 忙碌 + 等待的任务数量
+		{Autoscaler{Max: 8}, 2, 2, 20, 8},                   // 不超过Max
+		{Autoscaler{Max: 8, TasksPerWorker: 4}, 2, 2, 5, 4}, // 每4个等待的任务增加一个
+		{Autoscaler{Max: 8}, 6, 1, 0, 5},                    // 每次只减少一个
+		{Autoscaler{Min: 2, Max: 8}, 2, 0, 0, 2},            // 不少于Min
+		{Autoscaler{Max: 8}, 1, 0, 0, 1},                    // Min为0时视为1
+		{Autoscaler{Max: 8}, 3, 3, 0, 3},                    // 都在忙时保持不变
+	}
+
+	for _, test := range tests {
+		if target := test.a.Target(test.current, test.busy, test.queued); target != test.target {
+			t.Errorf("%+v.Target(%v, %v, %v) = %v, want %v", test.a, test.current, test.busy, test.queued, target, test.target)
+		}
+	}
+}
+
+func TestAutoscaler_Validate(t *testing.T) {
+	var tests = []struct {
+		a  Autoscaler
+		ok bool
+	}{
+		{Autoscaler{Max: 4}, true},
+		{Autoscaler{Min: 2, Max: 2}, true},
+		{Autoscaler{}, false},
+		{Autoscaler{Min: 4, Max: 2}, false},
+	}
+
+	for _, test := range tests {
+		if err := test.a.Validate(); (err == nil) != test.ok {
+			t.Errorf("%+v.Validate() = %v", test.a, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package language 记录每种编程语言的编译、运行方式
+package language
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 未指定语言时使用go
+const Default = "go"
+
+// 命令中的占位符，分别替换为源代码文件和可执行文件的路径
+const (
+	SourcePlaceholder = "{src}"
+	ExePlaceholder    = "{exe}"
+)
+
+type Language struct {
+	Name           string
+	CompilerImage  string   // 编译容器镜像，为空时使用Executor的编译容器镜像
+	CompileCmd     string   // 编译命令，例如 go build -o {exe} {src}，为空时为解释型语言，跳过编译阶段
+	CheckCmd       string   // 解释型语言的语法检查命令，可选，例如 node --check {exe}，失败视为CE
+	RunnerImage    string   // 运行容器镜像，为空时使用Executor的运行容器镜像
+	RunCmd         string   // 运行命令，例如 {exe}
+	SourceExt      string   // 源代码文件后缀，去掉后缀即为可执行文件路径
+	TimeMultiplier float64  // 时间限制的倍数，为0时视为1
+	Syscalls       []string // 运行时在默认的seccomp白名单之外允许的系统调用
+	Security       Security // 运行时的网络、进程数量、用户等隔离设置
+}
+
+// 替换占位符后的编译命令
+func (l *Language) CompileCommand(src, exe string) string {
+	return strings.NewReplacer(SourcePlaceholder, src, ExePlaceholder, exe).Replace(l.CompileCmd)
+}
+
+// 替换占位符后的运行命令
+func (l *Language) RunCommand(exe string) string {
+	return strings.Replace(l.RunCmd, ExePlaceholder, exe, -1)
+}
+
+// 替换占位符后的语法检查命令
+func (l *Language) CheckCommand(exe string) string {
+	return strings.Replace(l.CheckCmd, ExePlaceholder, exe, -1)
+}
+
+// 解释型语言没有编译阶段，直接运行源代码
+func (l *Language) Interpreted() bool {
+	return l.CompileCmd == ""
+}
+
+// 该语言实际的时间限制
+func (l *Language) Timeout(timeout float64) float64 {
+	if l.TimeMultiplier <= 0 {
+		return timeout
+	}
+	return timeout * l.TimeMultiplier
+}
+
+// 源代码文件路径对应的可执行文件路径
+func (l *Language) ExePath(codePath string) string {
+	return strings.TrimSuffix(codePath, l.SourceExt)
+}
+
+var (
+	mu        sync.RWMutex
+	languages = map[string]*Language{}
+)
+
+// 注册语言，同名语言会被覆盖
+func Register(l *Language) {
+	mu.Lock()
+	defer mu.Unlock()
+	languages[l.Name] = l
+}
+
+// 获取语言，name为空时返回Default
+func Get(name string) (*Language, error) {
+	if name == "" {
+		name = Default
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	l, ok := languages[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %v", name)
+	}
+	return l, nil
+}
+
+func init() {
+	// 运行容器默认为alpine(musl)，C、C++、Rust 需要静态链接
+	Register(&Language{
+		Name:       "go",
+		CompileCmd: "go build -o {exe} {src}",
+		RunCmd:     "{exe}",
+		SourceExt:  ".go",
+	})
+	Register(&Language{
+		Name:          "c",
+		CompilerImage: "gcc:10",
+		CompileCmd:    "gcc -O2 -static -std=c11 -o {exe} {src} -lm",
+		RunCmd:        "{exe}",
+		SourceExt:     ".c",
+	})
+	Register(&Language{
+		Name:          "cpp",
+		CompilerImage: "gcc:10",
+		CompileCmd:    "g++ -O2 -static -std=c++17 -o {exe} {src}",
+		RunCmd:        "{exe}",
+		SourceExt:     ".cpp",
+	})
+	// 编译结果为目录，源代码中的类名必须为Main；每次编译使用单独的临时目录，避免并发编译相互影响
+	Register(&Language{
+		Name:          "java",
+		CompilerImage: "openjdk:11",
+		CompileCmd: "d=$(mktemp -d) && cp {src} $d/Main.java && " +
+			"mkdir -p {exe} && javac -encoding UTF-8 -d {exe} $d/Main.java; code=$?; rm -rf $d; exit $code",
+		RunnerImage:    "openjdk:11-jre-slim",
+		RunCmd:         "java -cp {exe} Main",
+		SourceExt:      ".java",
+		TimeMultiplier: 2,
+		Syscalls:       []string{"get_mempolicy", "mbind"}, // JVM 启动时查询NUMA信息
+		Security:       Security{PidsLimit: 512},           // JVM 的GC、JIT线程数量与CPU核数有关
+	})
+	// 解释型语言，{exe} 为源代码文件
+	Register(&Language{
+		Name:           "python",
+		CheckCmd:       "python3 -c 'import ast, sys; ast.parse(open(sys.argv[1]).read(), sys.argv[1])' {exe}",
+		RunnerImage:    "python:3.9-alpine",
+		RunCmd:         "python3 {exe}",
+		SourceExt:      ".py",
+		TimeMultiplier: 3,
+	})
+	Register(&Language{
+		Name:           "javascript",
+		CheckCmd:       "node --check {exe}",
+		RunnerImage:    "node:14-alpine",
+		RunCmd:         "node {exe}",
+		SourceExt:      ".js",
+		TimeMultiplier: 2,
+	})
+	Register(&Language{
+		Name:          "rust",
+		CompilerImage: "rust:1.49",
+		CompileCmd:    "rustc -O -C target-feature=+crt-static -o {exe} {src}",
+		RunCmd:        "{exe}",
+		SourceExt:     ".rs",
+	})
+}
@@ -0,0 +1,43 @@
+package language
+
+import "testing"
+
+func TestLanguage(t *testing.T) {
+	lang, err := Get("")
+	if err != nil || lang.Name != Default {
+		t.Fatalf("Get(\"\") = %v, %v", lang, err)
+	}
+
+	if cmd := lang.CompileCommand("/code/1/success.go", "/exe/1/success"); cmd != "go build -o /exe/1/success /code/1/success.go" {
+		t.Errorf("CompileCommand = %q", cmd)
+	}
+	if exe := lang.ExePath("1/success.go"); exe != "1/success" {
+		t.Errorf("ExePath = %q", exe)
+	}
+
+	java, err := Get("java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd := java.RunCommand("/exe"); cmd != "java -cp /exe Main" {
+		t.Errorf("RunCommand = %q", cmd)
+	}
+	if timeout := java.Timeout(1.5); timeout != 3 {
+		t.Errorf("Timeout = %v", timeout)
+	}
+	if java.Security.Pids() != 512 || lang.Security.Pids() != DefaultPidsLimit || lang.Security.RunUser() != DefaultUser {
+		t.Errorf("Security = %+v, %+v", java.Security, lang.Security)
+	}
+
+	python, err := Get("python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !python.Interpreted() || lang.Interpreted() {
+		t.Error("only python should be interpreted")
+	}
+
+	if _, err = Get("brainfuck"); err == nil {
+		t.Error("expect error for unsupported language")
+	}
+}
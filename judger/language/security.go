@@ -0,0 +1,30 @@
+package language
+
+// 运行提交程序时的默认限制
+const (
+	DefaultPidsLimit = 64            // 进程(线程)数量限制
+	DefaultUser      = "65534:65534" // nobody
+)
+
+// 运行提交程序时的隔离设置，零值为最严格的设置，按语言放宽
+type Security struct {
+	Network      bool     // 允许访问网络，默认禁用
+	PidsLimit    int64    // 进程(线程)数量限制，为0时为 DefaultPidsLimit
+	User         string   // 运行程序的用户，格式为 uid:gid，为空时为 DefaultUser
+	WritableRoot bool     // 根文件系统可写，默认只读
+	CapAdd       []string // 保留的capabilities，默认去掉全部
+}
+
+func (s Security) Pids() int64 {
+	if s.PidsLimit <= 0 {
+		return DefaultPidsLimit
+	}
+	return s.PidsLimit
+}
+
+func (s Security) RunUser() string {
+	if s.User == "" {
+		return DefaultUser
+	}
+	return s.User
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// 绕过head直接打开/output下的文件写入超出输出限制的内容
+func main() {
+	files, _ := filepath.Glob("/output/*")
+	buf := make([]byte, 1<<20)
+	for _, name := range files {
+		f, err := os.OpenFile(name, os.O_WRONLY, 0)
+		if err != nil {
+			continue
+		}
+		for i := 0; i < 32; i++ {
+			if _, err := f.Write(buf); err != nil {
+				break
+			}
+		}
+		f.Close()
+	}
+}
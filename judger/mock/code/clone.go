@@ -0,0 +1,15 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// 以clone创建新的用户命名空间，被seccomp杀死，触发RF
+func main() {
+	pid, _, errno := syscall.RawSyscall(syscall.SYS_CLONE, uintptr(syscall.CLONE_NEWUSER|syscall.SIGCHLD), 0, 0)
+	if pid == 0 && errno == 0 {
+		syscall.RawSyscall(syscall.SYS_EXIT_GROUP, 0, 0, 0)
+	}
+	fmt.Println(pid, errno)
+}
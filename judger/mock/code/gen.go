@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+)
+
+// 对拍的生成器，以种子作为参数
+func main() {
+	seed, _ := strconv.ParseInt(os.Args[1], 10, 64)
+	r := rand.New(rand.NewSource(seed))
+	n := r.Intn(10) + 1
+	fmt.Println(n)
+	for i := 0; i < n; i++ {
+		fmt.Println(r.Intn(20)-10, r.Intn(20)-10)
+	}
+}
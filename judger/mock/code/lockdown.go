@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// 输出运行环境的隔离情况，与 answer/lockdown.txt 比较
+func main() {
+	fmt.Println("uid", os.Getuid(), os.Getgid())
+
+	var st syscall.Statfs_t
+	syscall.Statfs("/", &st)
+	fmt.Println("readonly", st.Flags&1 == 1) // ST_RDONLY
+
+	status, _ := ioutil.ReadFile("/proc/self/status")
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, "CapEff:") || strings.HasPrefix(line, "NoNewPrivs:") {
+			fmt.Println(strings.Join(strings.Fields(line), " "))
+		}
+	}
+
+	// 禁用网络时只有lo
+	var interfaces []string
+	dev, _ := ioutil.ReadFile("/proc/self/net/dev")
+	for _, line := range strings.Split(string(dev), "\n") {
+		if i := strings.Index(line, ":"); i > 0 {
+			interfaces = append(interfaces, strings.TrimSpace(line[:i]))
+		}
+	}
+	fmt.Println("interfaces", strings.Join(interfaces, " "))
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"fmt"
+)
+
+// 无限输出，触发OLE
+func main() {
+	for {
+		fmt.Println("1234567890")
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// 与success.go相同，但/dev/shm中有上一次运行留下的文件时输出错误答案
+func main() {
+	if _, err := os.Stat("/dev/shm/tgoj"); err == nil {
+		fmt.Println("leaked")
+		return
+	}
+	ioutil.WriteFile("/dev/shm/tgoj", []byte("1"), 0644)
+
+	var n int
+	fmt.Scanf("%d", &n)
+	for n > 0 {
+		n--
+		var a, b int
+		fmt.Scanf("%d %d", &a, &b)
+		fmt.Println(a + b)
+	}
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// 创建socket，被seccomp杀死，触发RF
+func main() {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	fmt.Println(fd, err)
+}
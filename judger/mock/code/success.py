@@ -0,0 +1,7 @@
+import sys
+
+# 运行容器会把输入合并为一行，按空白分隔读取
+data = list(map(int, sys.stdin.read().split()))
+n = data[0]
+for i in range(n):
+    print(data[1 + 2 * i] + data[2 + 2 * i])
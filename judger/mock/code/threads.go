@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// 创建大量线程，超出进程数量限制后go运行时退出，触发RE
+func main() {
+	for i := 0; i < 1000; i++ {
+		go func() {
+			runtime.LockOSThread()
+			time.Sleep(time.Hour)
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	fmt.Println("threads created")
+}
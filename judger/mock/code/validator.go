@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// 检查两数之和的输入数据：第一行为n，之后n行每行两个 [-10000, 10000] 内的整数
+func main() {
+	reader := bufio.NewReader(os.Stdin)
+	var n int
+	if _, err := fmt.Fscanln(reader, &n); err != nil || n < 0 {
+		fail("line 1: invalid n")
+	}
+	for i := 0; i < n; i++ {
+		var a, b int
+		if _, err := fmt.Fscanln(reader, &a, &b); err != nil {
+			fail(fmt.Sprintf("line %v: %v", i+2, err))
+		}
+		if a < -10000 || a > 10000 || b < -10000 || b > 10000 {
+			fail(fmt.Sprintf("line %v: value out of range", i+2))
+		}
+	}
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+	os.Exit(3)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+)
+
+// 两数相等时输出错误的结果，用于对拍
+func main() {
+	var n int
+	fmt.Scanf("%d", &n)
+	for n > 0 {
+		n--
+		var a, b int
+		fmt.Scanf("%d %d", &a, &b)
+		if a == b {
+			fmt.Println(a)
+		} else {
+			fmt.Println(a + b)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package judger
+
+import "tgoj/judger/errors"
+
+// 子任务，所有用例都通过才得分，没有用例时不得分；special judge 给出部分得分时，按用例中最低的得分比例计分
+type Subtask struct {
+	Name  string
+	Score float64 // 子任务的分值
+	Cases []int   // 包含的用例在 Task.TestCases 中的下标，一个用例可以属于多个子任务
+}
+
+// 单个子任务的得分
+type SubtaskResult struct {
+	Name     string
+	Score    float64
+	MaxScore float64
+	Verdict  errors.JudgerError // 第一个未通过用例的结果，全部通过则为AC
+}
+
+// 每个用例的分值：所有用例都没有设置Weight时每个用例为1；
+// 否则按Weight计分，Weight为0(或负数)的用例不计分，例如样例
+func (t *Task) weights() []float64 {
+	weighted := false
+	for _, c := range t.TestCases {
+		if c.Weight > 0 {
+			weighted = true
+			break
+		}
+	}
+
+	w := make([]float64, len(t.TestCases))
+	for i, c := range t.TestCases {
+		switch {
+		case !weighted:
+			w[i] = 1
+		case c.Weight > 0:
+			w[i] = c.Weight
+		}
+	}
+	return w
+}
+
+// 满分：所有子任务的分值 加上 不属于任何子任务的用例的分值
+func (t *Task) MaxScore() float64 {
+	var max float64
+	for _, s := range t.Subtasks {
+		max += s.Score
+	}
+	inSubtask := t.casesInSubtask()
+	for i, w := range t.weights() {
+		if !inSubtask[i] {
+			max += w
+		}
+	}
+	return max
+}
+
+// 按子任务和用例的分值计算总分 及 每个子任务的得分
+func (t *Task) score(cases []CaseResult) (total float64, subtasks []SubtaskResult) {
+	for _, s := range t.Subtasks {
+		res := SubtaskResult{Name: s.Name, MaxScore: s.Score, Verdict: errors.AC}
+		ratio := 1.0
+		// 没有用例的子任务不得分
+		if len(s.Cases) == 0 {
+			ratio, res.Verdict = 0, errors.UNKNOWN
+		}
+		for _, i := range s.Cases {
+			if i < 0 || i >= len(cases) {
+				ratio = 0
+				res.Verdict = errors.UNKNOWN
+				break
+			}
+			if cases[i].Score < ratio {
+				ratio = cases[i].Score
+			}
+			if res.Verdict == errors.AC && cases[i].Verdict != errors.AC {
+				res.Verdict = cases[i].Verdict
+			}
+		}
+		res.Score = s.Score * ratio
+		total += res.Score
+		subtasks = append(subtasks, res)
+	}
+
+	inSubtask := t.casesInSubtask()
+	for i, w := range t.weights() {
+		if !inSubtask[i] && i < len(cases) {
+			total += w * cases[i].Score
+		}
+	}
+	return total, subtasks
+}
+
+// 属于某个子任务的用例
+func (t *Task) casesInSubtask() map[int]bool {
+	in := make(map[int]bool)
+	for _, s := range t.Subtasks {
+		for _, i := range s.Cases {
+			in[i] = true
+		}
+	}
+	return in
+}
@@ -0,0 +1,58 @@
+package judger
+
+import (
+	"testing"
+	"tgoj/judger/errors"
+)
+
+func TestScore(t *testing.T) {
+	ac := CaseResult{Verdict: errors.AC, Score: 1}
+	wa := CaseResult{Verdict: errors.WA}
+	pc := CaseResult{Verdict: errors.PC, Score: 0.5}
+
+	var tests = []struct {
+		task     Task
+		cases    []CaseResult
+		score    float64
+		maxScore float64
+		subtasks []SubtaskResult
+	}{
+		{Task{TestCases: make([]TestCase, 3)}, []CaseResult{ac, wa, pc}, 1.5, 3, nil},
+		{Task{TestCases: []TestCase{{Weight: 10}, {Weight: 30}}}, []CaseResult{wa, ac}, 30, 40, nil},
+		{
+			Task{
+				TestCases: make([]TestCase, 4),
+				Subtasks:  []Subtask{{"1", 20, []int{0, 1}}, {"2", 30, []int{1, 2}}},
+			},
+			[]CaseResult{ac, ac, wa, ac}, 21, 51,
+			[]SubtaskResult{{"1", 20, 20, errors.AC}, {"2", 0, 30, errors.WA}},
+		},
+		// 设置了分值时，分值为0的样例不计分
+		{Task{TestCases: []TestCase{{}, {Weight: 30}, {Weight: 70}}}, []CaseResult{wa, ac, pc}, 65, 100, nil},
+		{Task{TestCases: []TestCase{{}, {Weight: 50}}}, []CaseResult{ac, wa}, 0, 50, nil},
+		// 没有用例的子任务不得分
+		{
+			Task{TestCases: make([]TestCase, 1), Subtasks: []Subtask{{"empty", 50, nil}, {"1", 50, []int{0}}}},
+			[]CaseResult{ac}, 50, 100,
+			[]SubtaskResult{{"empty", 0, 50, errors.UNKNOWN}, {"1", 50, 50, errors.AC}},
+		},
+		{
+			Task{TestCases: make([]TestCase, 2), Subtasks: []Subtask{{"1", 40, []int{0, 1}}}},
+			[]CaseResult{ac, pc}, 20, 40,
+			[]SubtaskResult{{"1", 20, 40, errors.PC}},
+		},
+	}
+
+	for _, test := range tests {
+		r := NewResult(&test.task, test.cases)
+		if r.Score != test.score || r.MaxScore != test.maxScore || len(r.Subtasks) != len(test.subtasks) {
+			t.Errorf("NewResult(%+v) = %v", test.task, r)
+			continue
+		}
+		for i := range r.Subtasks {
+			if r.Subtasks[i] != test.subtasks[i] {
+				t.Errorf("subtask %v = %+v, want %+v", i, r.Subtasks[i], test.subtasks[i])
+			}
+		}
+	}
+}
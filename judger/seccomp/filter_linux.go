@@ -0,0 +1,95 @@
+package seccomp
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	retKillProcess = 0x80000000 // SECCOMP_RET_KILL_PROCESS
+	retAllow       = 0x7fff0000 // SECCOMP_RET_ALLOW
+	retErrno       = 0x00050000 // SECCOMP_RET_ERRNO，低16位为错误码
+	x32SyscallBit  = 0x40000000 // x32 ABI 的系统调用号
+	prSetSeccomp   = 22         // PR_SET_SECCOMP
+	prSetNoNewPriv = 38         // PR_SET_NO_NEW_PRIVS
+	modeFilter     = 2          // SECCOMP_MODE_FILTER
+	// struct seccomp_data 中 nr 和 arch 的偏移
+	offsetNr   = 0
+	offsetArch = 4
+	offsetArg0 = 16 // args[0] 的低32位，只支持小端的amd64、arm64
+)
+
+// 生成cBPF程序：架构不匹配 或 系统调用不在白名单中时杀死进程
+//
+//	ld arch; jeq auditArch ? next : kill
+//	ld nr; jge x32SyscallBit ? kill : next
+//	jeq nr1 ? allow : next ... ret kill
+//	clone:  jeq nr ? next : skip; ld args[0]; jset CloneNamespaceFlags ? kill : allow
+//	clone3: jeq nr ? errno(ENOSYS) : next
+func (p *Profile) Filter() ([]syscall.SockFilter, error) {
+	if syscallNumbers == nil {
+		return nil, fmt.Errorf("seccomp is not supported on this architecture")
+	}
+
+	filter := []syscall.SockFilter{
+		stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, offsetArch),
+		jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, auditArch, 1, 0),
+		stmt(syscall.BPF_RET|syscall.BPF_K, retKillProcess),
+		stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, offsetNr),
+		jump(syscall.BPF_JMP|syscall.BPF_JGE|syscall.BPF_K, x32SyscallBit, 0, 1),
+		stmt(syscall.BPF_RET|syscall.BPF_K, retKillProcess),
+	}
+	// 每个系统调用只有几条指令，避免跳转距离超过255
+	for _, name := range p.Allow {
+		nr, ok := syscallNumbers[name]
+		if !ok {
+			continue
+		}
+		switch name {
+		case "clone":
+			// 读取参数后累加器不再是系统调用号，两个分支都直接返回
+			filter = append(filter,
+				jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, uint32(nr), 0, 4),
+				stmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, offsetArg0),
+				jump(syscall.BPF_JMP|syscall.BPF_JSET|syscall.BPF_K, CloneNamespaceFlags, 0, 1),
+				stmt(syscall.BPF_RET|syscall.BPF_K, retKillProcess),
+				stmt(syscall.BPF_RET|syscall.BPF_K, retAllow))
+		case "clone3":
+			filter = append(filter,
+				jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, uint32(nr), 0, 1),
+				stmt(syscall.BPF_RET|syscall.BPF_K, retErrno|errnoENOSYS))
+		default:
+			filter = append(filter,
+				jump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, uint32(nr), 0, 1),
+				stmt(syscall.BPF_RET|syscall.BPF_K, retAllow))
+		}
+	}
+	return append(filter, stmt(syscall.BPF_RET|syscall.BPF_K, retKillProcess)), nil
+}
+
+// 为当前线程设置 no_new_privs 并加载过滤器，之后创建的子进程继承该过滤器
+// 调用前需要 runtime.LockOSThread，过滤器只对当前线程生效
+func (p *Profile) Apply() error {
+	filter, err := p.Filter()
+	if err != nil {
+		return err
+	}
+	prog := syscall.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPriv, 1, 0); errno != 0 {
+		return fmt.Errorf("set no_new_privs: %v", errno)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetSeccomp, modeFilter, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("set seccomp filter: %v", errno)
+	}
+	return nil
+}
+
+func stmt(code uint16, k uint32) syscall.SockFilter {
+	return syscall.SockFilter{Code: code, K: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) syscall.SockFilter {
+	return syscall.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
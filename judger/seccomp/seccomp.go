@@ -0,0 +1,119 @@
+// Package seccomp 定义运行提交程序时允许的系统调用，调用白名单之外的系统调用时进程被杀死(SIGSYS)，判定为RF
+package seccomp
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// 默认允许的系统调用，包括go、C/C++、java、python、node 以及 sh、timeout、head 等命令需要的系统调用
+// 不包括网络、ptrace、挂载、命名空间、内核模块、bpf、perf、密钥管理、重启等
+// 不存在于当前架构的系统调用会被忽略；clone 和 clone3 有额外的限制，见 CloneNamespaceFlags
+var DefaultAllow = []string{
+	// 文件
+	"read", "write", "readv", "writev", "pread64", "pwrite64", "preadv", "pwritev", "preadv2", "pwritev2",
+	"open", "openat", "creat", "close", "close_range", "lseek", "_llseek", "dup", "dup2", "dup3", "fcntl",
+	"pipe", "pipe2", "ioctl", "flock", "fsync", "fdatasync", "truncate", "ftruncate", "fallocate", "fadvise64",
+	"readahead", "sendfile", "splice", "tee", "vmsplice", "copy_file_range", "sync", "sync_file_range",
+	"stat", "fstat", "lstat", "newfstatat", "fstatat64", "statx", "statfs", "fstatfs",
+	"access", "faccessat", "faccessat2", "getdents", "getdents64", "getcwd", "chdir", "fchdir",
+	"rename", "renameat", "renameat2", "mkdir", "mkdirat", "rmdir", "link", "linkat", "unlink", "unlinkat",
+	"symlink", "symlinkat", "readlink", "readlinkat", "chmod", "fchmod", "fchmodat", "chown", "fchown",
+	"fchownat", "lchown", "umask", "utime", "utimes", "utimensat", "futimesat", "memfd_create",
+	// 多路复用
+	"select", "pselect6", "poll", "ppoll", "epoll_create", "epoll_create1", "epoll_ctl", "epoll_wait",
+	"epoll_pwait", "epoll_pwait2", "eventfd", "eventfd2", "signalfd", "signalfd4",
+	"timerfd_create", "timerfd_settime", "timerfd_gettime",
+	// 内存
+	"brk", "mmap", "munmap", "mremap", "mprotect", "msync", "mincore", "madvise", "mlock", "munlock",
+	"membarrier",
+	// 进程、线程
+	"clone", "clone3", "fork", "vfork", "execve", "exit", "exit_group", "wait4", "waitid",
+	"pidfd_open", "pidfd_send_signal", "getpid", "getppid", "gettid", "getpgid", "setpgid", "getpgrp", "getsid", "setsid",
+	"set_tid_address", "set_robust_list", "get_robust_list", "futex", "rseq", "arch_prctl", "prctl",
+	"set_thread_area", "get_thread_area", "sched_yield", "sched_getaffinity", "sched_setaffinity",
+	"sched_getparam", "sched_setparam", "sched_getscheduler", "sched_setscheduler",
+	"sched_get_priority_max", "sched_get_priority_min", "sched_rr_get_interval", "getcpu",
+	"getpriority", "setpriority", "getrlimit", "setrlimit", "prlimit64", "getrusage", "uname", "sysinfo",
+	"times", "restart_syscall",
+	// 用户
+	"getuid", "geteuid", "getgid", "getegid", "getresuid", "getresgid", "getgroups",
+	"setuid", "setgid", "setreuid", "setregid", "setresuid", "setresgid", "setgroups", "setfsuid", "setfsgid",
+	"capget", "capset",
+	// 信号
+	"rt_sigaction", "rt_sigprocmask", "rt_sigreturn", "rt_sigpending", "rt_sigtimedwait", "rt_sigqueueinfo",
+	"rt_sigsuspend", "sigaltstack", "kill", "tkill", "tgkill", "pause", "alarm",
+	// 时间
+	"time", "gettimeofday", "clock_gettime", "clock_getres", "clock_nanosleep", "nanosleep",
+	"getitimer", "setitimer", "timer_create", "timer_settime", "timer_gettime", "timer_getoverrun",
+	"timer_delete",
+	"getrandom",
+}
+
+// clone 的flags中创建命名空间的标志(CLONE_NEWNS|CLONE_NEWCGROUP|CLONE_NEWUTS|CLONE_NEWIPC|CLONE_NEWUSER|CLONE_NEWPID|CLONE_NEWNET)，
+// 与docker默认配置相同，包含这些标志的clone被杀死；clone3 的参数在内存中无法检查，返回ENOSYS，glibc、go 会退回到clone
+const CloneNamespaceFlags = 0x7E020000
+
+// clone3 返回的错误码 ENOSYS
+const errnoENOSYS = 38
+
+// 允许的系统调用白名单
+type Profile struct {
+	Allow []string
+}
+
+// 默认白名单加上extra，用于按语言增加允许的系统调用
+func New(extra ...string) *Profile {
+	allow := make([]string, 0, len(DefaultAllow)+len(extra))
+	seen := map[string]bool{}
+	for _, name := range append(append(allow, DefaultAllow...), extra...) {
+		if !seen[name] {
+			seen[name] = true
+			allow = append(allow, name)
+		}
+	}
+	sort.Strings(allow)
+	return &Profile{Allow: allow}
+}
+
+// docker seccomp 配置的格式
+type dockerProfile struct {
+	DefaultAction string          `json:"defaultAction"`
+	Syscalls      []dockerSyscall `json:"syscalls"`
+}
+
+type dockerSyscall struct {
+	Names    []string    `json:"names"`
+	Action   string      `json:"action"`
+	Args     []dockerArg `json:"args,omitempty"`
+	ErrnoRet uint        `json:"errnoRet,omitempty"`
+}
+
+type dockerArg struct {
+	Index    uint   `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo"`
+	Op       string `json:"op"`
+}
+
+// 用于 HostConfig.SecurityOpt 的配置，例如 seccomp={"defaultAction":...}
+// clone 只允许不含 CloneNamespaceFlags 的flags，clone3 返回ENOSYS
+func (p *Profile) SecurityOpt() string {
+	var allow []string
+	syscalls := []dockerSyscall{{Action: "SCMP_ACT_ALLOW"}}
+	for _, name := range p.Allow {
+		switch name {
+		case "clone":
+			syscalls = append(syscalls, dockerSyscall{Names: []string{name}, Action: "SCMP_ACT_ALLOW",
+				Args: []dockerArg{{Index: 0, Value: CloneNamespaceFlags, ValueTwo: 0, Op: "SCMP_CMP_MASKED_EQ"}}})
+		case "clone3":
+			syscalls = append(syscalls, dockerSyscall{Names: []string{name}, Action: "SCMP_ACT_ERRNO", ErrnoRet: errnoENOSYS})
+		default:
+			allow = append(allow, name)
+		}
+	}
+	syscalls[0].Names = allow
+
+	b, _ := json.Marshal(dockerProfile{DefaultAction: "SCMP_ACT_KILL_PROCESS", Syscalls: syscalls})
+	return "seccomp=" + string(b)
+}
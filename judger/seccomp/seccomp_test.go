@@ -0,0 +1,41 @@
+package seccomp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	p := New("socket", "read")
+	allowed := map[string]bool{}
+	for _, name := range p.Allow {
+		allowed[name] = true
+	}
+	if len(allowed) != len(p.Allow) || len(p.Allow) != len(New().Allow)+1 {
+		t.Errorf("New(socket, read) = %v", p.Allow)
+	}
+	for name, want := range map[string]bool{"read": true, "socket": true, "connect": false, "ptrace": false, "mount": false} {
+		if allowed[name] != want {
+			t.Errorf("allow %v = %v, want %v", name, allowed[name], want)
+		}
+	}
+
+	opt := p.SecurityOpt()
+	var profile dockerProfile
+	if !strings.HasPrefix(opt, "seccomp=") || json.Unmarshal([]byte(strings.TrimPrefix(opt, "seccomp=")), &profile) != nil {
+		t.Fatalf("SecurityOpt() = %v", opt)
+	}
+	// 允许的系统调用、带参数限制的clone、返回ENOSYS的clone3
+	if profile.DefaultAction != "SCMP_ACT_KILL_PROCESS" || len(profile.Syscalls) != 3 || len(profile.Syscalls[0].Names) != len(p.Allow)-2 {
+		t.Fatalf("SecurityOpt() = %v", opt)
+	}
+	clone, clone3 := profile.Syscalls[1], profile.Syscalls[2]
+	if clone.Names[0] != "clone" || clone.Action != "SCMP_ACT_ALLOW" || len(clone.Args) != 1 ||
+		clone.Args[0] != (dockerArg{Index: 0, Value: CloneNamespaceFlags, Op: "SCMP_CMP_MASKED_EQ"}) {
+		t.Errorf("clone rule = %+v", clone)
+	}
+	if clone3.Names[0] != "clone3" || clone3.Action != "SCMP_ACT_ERRNO" || clone3.ErrnoRet != 38 {
+		t.Errorf("clone3 rule = %+v", clone3)
+	}
+}
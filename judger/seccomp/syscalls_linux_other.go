@@ -0,0 +1,8 @@
+// +build linux,!amd64,!arm64
+
+package seccomp
+
+const auditArch = 0
+
+// 其他架构不支持seccomp
+var syscallNumbers map[string]int
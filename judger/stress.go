@@ -0,0 +1,63 @@
+package judger
+
+import (
+	"fmt"
+	"tgoj/judger/errors"
+	"tgoj/judger/verifier"
+)
+
+// 对拍中的一个程序
+type Program struct {
+	CodePath string // 相对code 的路径
+	Language string // 编程语言，为空时为go
+}
+
+// 对拍任务：生成器按种子生成输入，比较提交程序与标准程序的输出，找到第一个输出不同的种子
+// 三个程序都在运行容器中运行，使用相同的资源限制
+type StressTask struct {
+	ID              int64
+	Generator       Program // 以种子作为唯一的参数运行，stdout作为输入
+	Reference       Program // 标准程序，输出作为答案
+	Submission      Program
+	FirstSeed       int64
+	Seeds           int // 运行的种子数量，种子依次为 FirstSeed、FirstSeed+1 ...
+	CpuPeriod       int64
+	CpuQuota        int64
+	Timeout         float64           // second
+	Memory          int64             // in KB
+	Verifier        verifier.Verifier // 比较输出使用的校验器，同 Task.Verifier
+	VerifierName    string
+	VerifierOptions verifier.Options
+}
+
+// 生成运行其中一个程序的任务
+func (t *StressTask) Task(p Program) *Task {
+	return &Task{
+		ID:              t.ID,
+		CodePath:        p.CodePath,
+		Language:        p.Language,
+		CpuPeriod:       t.CpuPeriod,
+		CpuQuota:        t.CpuQuota,
+		Timeout:         t.Timeout,
+		Memory:          t.Memory,
+		Verifier:        t.Verifier,
+		VerifierName:    t.VerifierName,
+		VerifierOptions: t.VerifierOptions,
+		Status:          CREATED,
+	}
+}
+
+type StressResult struct {
+	ID      int64
+	Found   bool               // 是否找到提交程序未通过的种子，提交程序编译失败时为false
+	Seed    int64              // 第一个未通过的种子
+	Seeds   int                // 已运行的种子数量
+	Verdict errors.JudgerError // 提交程序在该种子上的结果；生成器、标准程序出错时为错误的类型
+	Input   string             // 该种子生成的输入，相对input 的路径，保留用于复现
+	Error   error              // 提交程序在该种子上的错误，或 生成器、标准程序出错导致无法继续对拍的错误
+}
+
+func (r StressResult) String() string {
+	return fmt.Sprintf("ID: %v, Found: %v, Seed: %v, Seeds: %v, Verdict: %v, Input: %v, Error: %v",
+		r.ID, r.Found, r.Seed, r.Seeds, r.Verdict, r.Input, r.Error)
+}
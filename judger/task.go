@@ -1,6 +1,11 @@
 package judger
 
-import "fmt"
+import (
+	"fmt"
+	"tgoj/judger/errors"
+	"tgoj/judger/verifier"
+	"time"
+)
 
 type TaskStatus int
 
@@ -11,27 +16,87 @@ const (
 	FINISH              // 判题完成
 )
 
+// 一个测试用例，每个用例单独运行、单独校验
+type TestCase struct {
+	InputPath  string  // 相对input 的路径
+	AnswerPath string  // 相对answer 的路径
+	OutputPath string  // 相对output 的路径
+	Weight     float64 // 用例的分值，所有用例都为0时每个用例为1，否则为0的用例不计分；属于子任务的用例按子任务计分
+}
+
 type Task struct {
-	ID         int64
-	CodePath   string // 相对code 的路径
-	AnswerPath string // 相对answer 的路径
-	InputPath  string // 相对input 的路径
-	OutputPath string // 相对output 的路径
-	ExePath    string // 相对exe 的路径
-	CpuPeriod  int64
-	CpuQuota   int64
-	Timeout    float64 // second
-	Memory     int64   // in KB
-	Status     TaskStatus
+	ID              int64
+	CodePath        string // 相对code 的路径
+	Language        string // 编程语言，为空时为go，支持的语言见language包
+	ExePath         string // 相对exe 的路径
+	TestCases       []TestCase
+	Subtasks        []Subtask // 子任务，为空时按每个用例的分值计分
+	CpuPeriod       int64
+	CpuQuota        int64
+	Timeout         float64           // second
+	Memory          int64             // in KB
+	OutputLimit     int64             // 每个用例输出文件的大小限制，单位 byte，为0时使用Executor的默认值
+	FailFast        bool              // 出现第一个未通过的用例后不再运行后续用例，后续用例标记为SKIPPED
+	Interactor      string            // 交互题的interactor，相对interactor目录的路径，为空时不是交互题
+	Verifier        verifier.Verifier // 该题目使用的校验器，为nil时使用 VerifierName
+	VerifierName    string            // 校验器名称，见verifier包的注册表，与Verifier都为空时使用Executor的校验器
+	VerifierOptions verifier.Options  // 创建 VerifierName 对应校验器的参数
+	HideMismatch    bool              // 不在结果中给出输出与答案的差异，用于比赛
+	Stream          bool              // 流式校验，程序的stdout直接交给校验器，不写入输出文件，校验器需要实现 verifier.StreamVerifier
+	Status          TaskStatus
+}
+
+// 单个用例的评测结果
+type CaseResult struct {
+	Verdict  errors.JudgerError
+	CpuTime  time.Duration
+	WallTime time.Duration
+	Memory   int64              // 峰值内存，单位 byte
+	Passed   int                // Verifier 返回的通过数量
+	Score    float64            // 得分比例，范围 [0, 1]，special judge 可以给出部分得分
+	Mismatch *verifier.Mismatch // WA时输出与答案第一个不同的位置
+	Error    error
+}
+
+func (c CaseResult) String() string {
+	return fmt.Sprintf("%v, %vms, %.1fMB", c.Verdict,
+		c.CpuTime.Milliseconds(), float64(c.Memory)/(1<<20))
 }
 
 type Result struct {
-	ID      int64
-	Success bool
+	ID       int64
+	Success  bool
+	Verdict  errors.JudgerError // 第一个未通过用例的结果，全部通过则为AC
+	Score    float64            // 总分
+	MaxScore float64            // 满分
+	Cases    []CaseResult
+	Subtasks []SubtaskResult
 	//Message string // error when running executable, eg: OOM
 	Error error // error when executing command
 }
 
+// 汇总每个用例的结果，并按子任务和用例的分值计分
+func NewResult(task *Task, cases []CaseResult) Result {
+	r := Result{
+		ID:       task.ID,
+		Success:  true,
+		Verdict:  errors.AC,
+		MaxScore: task.MaxScore(),
+		Cases:    cases,
+	}
+	r.Score, r.Subtasks = task.score(cases)
+	for _, c := range cases {
+		if c.Verdict != errors.AC {
+			r.Success = false
+			r.Verdict = c.Verdict
+			r.Error = c.Error
+			break
+		}
+	}
+	return r
+}
+
 func (r Result) String() string {
-	return fmt.Sprintf("ID: %v, Success: %v, Error: %v", r.ID, r.Success, r.Error)
+	return fmt.Sprintf("ID: %v, Success: %v, Verdict: %v, Score: %v/%v, Cases: %v, Error: %v",
+		r.ID, r.Success, r.Verdict, r.Score, r.MaxScore, r.Cases, r.Error)
 }
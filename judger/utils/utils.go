@@ -22,3 +22,40 @@ func CheckDirectoryExist(path string) {
 		os.MkdirAll(path, os.ModePerm)
 	}
 }
+
+// 只保留写入内容的前head个字节和最后tail个字节，防止程序大量输出占用内存
+type HeadTailBuffer struct {
+	head, tail []byte
+	headSize   int
+	tailSize   int
+	dropped    bool
+}
+
+func NewHeadTailBuffer(headSize, tailSize int) *HeadTailBuffer {
+	return &HeadTailBuffer{headSize: headSize, tailSize: tailSize}
+}
+
+func (b *HeadTailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if left := b.headSize - len(b.head); left > 0 {
+		if left > len(p) {
+			left = len(p)
+		}
+		b.head = append(b.head, p[:left]...)
+		p = p[left:]
+	}
+
+	b.tail = append(b.tail, p...)
+	if over := len(b.tail) - b.tailSize; over > 0 {
+		b.tail = append(b.tail[:0], b.tail[over:]...)
+		b.dropped = true
+	}
+	return n, nil
+}
+
+func (b *HeadTailBuffer) String() string {
+	if b.dropped {
+		return string(b.head) + "\n...\n" + string(b.tail)
+	}
+	return string(b.head) + string(b.tail)
+}
@@ -0,0 +1,13 @@
+package judger
+
+import "fmt"
+
+// 未通过validator校验的输入文件
+type InvalidInput struct {
+	Input string // 相对input 的路径
+	Msg   string // validator 给出的信息
+}
+
+func (e InvalidInput) Error() string {
+	return fmt.Sprintf("invalid input %v: %v", e.Input, e.Msg)
+}
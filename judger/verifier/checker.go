@@ -0,0 +1,133 @@
+package verifier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"tgoj/judger/errors"
+)
+
+// testlib 的退出码
+const (
+	testlibOK            = 0
+	testlibWA            = 1
+	testlibPE            = 2
+	testlibFail          = 3
+	testlibDirt          = 4
+	testlibPoints        = 7
+	testlibUnexpectedEOF = 8
+	testlibPartially     = 16 // 16+n 表示得分为 n%
+)
+
+// 需要输入文件的校验器，executor 会优先调用 VerifyInput
+type InputVerifier interface {
+	Verifier
+	VerifyInput(inputFileName, outputFileName, answerFileName string) (int, error)
+}
+
+// 在隔离环境中运行题目提供的程序，由executor实现
+type Sandbox interface {
+	// 以 checker <input> <output> <answer> 的方式运行checker，返回checker的退出码和输出的信息
+	RunChecker(checker, inputFileName, outputFileName, answerFileName string) (exitCode int, msg string, err error)
+}
+
+// special judge，运行题目提供的checker判断输出是否正确，兼容testlib的退出码
+// 部分得分时checker以 points 退出，信息以得分比例开头，例如 "0.5 half of the answers are correct"
+type CheckerVerifier struct {
+	Checker string  // checker 可执行文件，相对checker目录的路径
+	Sandbox Sandbox // 为nil时由executor设置
+	// 运行checker前的规范化处理，启用时把规范化后的输出和答案写入临时文件交给checker
+	Normalize Normalization
+}
+
+func (c CheckerVerifier) Verify(outputFileName, answerFileName string) (int, error) {
+	return 0, errors.New(errors.ENV, "checker requires input file")
+}
+
+func (c CheckerVerifier) VerifyInput(inputFileName, outputFileName, answerFileName string) (int, error) {
+	if c.Sandbox == nil {
+		return 0, errors.New(errors.ENV, "checker sandbox not set")
+	}
+
+	if c.Normalize.enabled() {
+		output, removeOutput, err := normalizedCopy(outputFileName, c.Normalize)
+		if err != nil {
+			return 0, errors.New(errors.OutputNotFound, fmt.Sprintf("%v not found", outputFileName))
+		}
+		defer removeOutput()
+
+		answer, removeAnswer, err := normalizedCopy(answerFileName, c.Normalize)
+		if err != nil {
+			return 0, errors.New(errors.AnswerNotFound, fmt.Sprintf("%v not found", answerFileName))
+		}
+		defer removeAnswer()
+		outputFileName, answerFileName = output, answer
+	}
+
+	code, msg, err := c.Sandbox.RunChecker(c.Checker, inputFileName, outputFileName, answerFileName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = TestlibResult(code, msg); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// 把testlib程序(checker、interactor)的退出码转换为校验结果
+func TestlibResult(exitCode int, msg string) error {
+	msg = strings.TrimSpace(msg)
+	switch {
+	case exitCode == testlibOK:
+		return nil
+	case exitCode == testlibWA, exitCode == testlibUnexpectedEOF:
+		return errors.New(errors.WA, msg)
+	case exitCode == testlibPE, exitCode == testlibDirt:
+		return errors.New(errors.PE, msg)
+	case exitCode == testlibPoints:
+		return partial(parseScore(msg), msg)
+	case exitCode >= testlibPartially && exitCode <= testlibPartially+100:
+		return partial(float64(exitCode-testlibPartially)/100, msg)
+	case exitCode == testlibFail:
+		return errors.New(errors.ENV, fmt.Sprintf("checker failed: %v", msg))
+	default:
+		return errors.New(errors.ENV, fmt.Sprintf("checker exited with code %v: %v", exitCode, msg))
+	}
+}
+
+// 部分正确，Score 为得分比例，范围 [0, 1]
+type PartialError struct {
+	Score float64
+	Msg   string
+}
+
+func (e PartialError) Error() string {
+	return fmt.Sprintf("partially correct (%v): %v", e.Score, e.Msg)
+}
+
+func partial(score float64, msg string) error {
+	if score >= 1 {
+		return nil
+	}
+	if score < 0 {
+		score = 0
+	}
+	return PartialError{Score: score, Msg: msg}
+}
+
+// 解析信息开头的得分比例，兼容 "points 0.5 ..." 的格式
+func parseScore(msg string) float64 {
+	fields := strings.Fields(msg)
+	if len(fields) > 0 && fields[0] == "points" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return 0
+	}
+	score, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return score
+}
@@ -0,0 +1,40 @@
+package verifier
+
+import (
+	"testing"
+	"tgoj/judger/errors"
+)
+
+type fakeSandbox struct {
+	code int
+	msg  string
+}
+
+func (s fakeSandbox) RunChecker(checker, inputFileName, outputFileName, answerFileName string) (int, string, error) {
+	return s.code, s.msg, nil
+}
+
+func TestCheckerVerifier(t *testing.T) {
+	var tests = []struct {
+		code    int
+		msg     string
+		verdict errors.JudgerError
+		score   float64
+	}{
+		{0, "ok 3 numbers", errors.AC, 1},
+		{1, "wrong answer 2nd numbers differ", errors.WA, 0},
+		{2, "extra tokens", errors.PE, 0},
+		{3, "answer file broken", errors.ENV, 0},
+		{7, "points 0.5 half", errors.PC, 0.5},
+		{7, "1", errors.AC, 1},
+		{16 + 30, "", errors.PC, 0.3},
+	}
+
+	for _, test := range tests {
+		c := CheckerVerifier{Checker: "1/checker", Sandbox: fakeSandbox{test.code, test.msg}}
+		_, err := c.VerifyInput("input", "output", "answer")
+		if Verdict(err) != test.verdict || Score(err) != test.score {
+			t.Errorf("exit code %v: got %v, %v, want %v, %v", test.code, Verdict(err), Score(err), test.verdict, test.score)
+		}
+	}
+}
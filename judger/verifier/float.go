@@ -0,0 +1,57 @@
+package verifier
+
+import (
+	"io"
+	"math"
+	"strconv"
+)
+
+// 未设置误差时使用的默认误差
+const DefaultEpsilon = 1e-6
+
+// 逐个比较以空白分隔的token，输出和答案都是数字时允许一定的误差，其他token必须完全相同
+// 满足绝对误差或相对误差其中之一即可，误差来自题目的 AbsEpsilon、RelEpsilon
+type FloatVerifier struct {
+	AbsEpsilon float64 // 绝对误差 |output - answer| <= AbsEpsilon
+	RelEpsilon float64 // 相对误差 |output - answer| <= RelEpsilon * |answer|
+	Normalize  Normalization
+}
+
+// 误差都为0时使用 DefaultEpsilon，并使用 DefaultNormalization
+func NewFloatVerifier(absEpsilon, relEpsilon float64) FloatVerifier {
+	if absEpsilon <= 0 && relEpsilon <= 0 {
+		absEpsilon, relEpsilon = DefaultEpsilon, DefaultEpsilon
+	}
+	return FloatVerifier{AbsEpsilon: absEpsilon, RelEpsilon: relEpsilon, Normalize: DefaultNormalization}
+}
+
+func (f FloatVerifier) Verify(outputFileName, answerFileName string) (int, error) {
+	return verifyTokenFiles(outputFileName, answerFileName, f.Normalize, f.equal)
+}
+
+func (f FloatVerifier) VerifyStream(output io.Reader, answerFileName string) (int, error) {
+	return verifyStream(output, answerFileName, f.Normalize, func(output, answer io.Reader) (int, error) {
+		return verifyTokens(output, answer, f.equal)
+	})
+}
+
+func (f FloatVerifier) equal(output, answer string) bool {
+	if output == answer {
+		return true
+	}
+
+	o, err := strconv.ParseFloat(output, 64)
+	if err != nil {
+		return false
+	}
+	a, err := strconv.ParseFloat(answer, 64)
+	if err != nil {
+		return false
+	}
+
+	if math.IsNaN(o) || math.IsNaN(a) || math.IsInf(o, 0) || math.IsInf(a, 0) {
+		return o == a
+	}
+	diff := math.Abs(o - a)
+	return diff <= f.AbsEpsilon || diff <= f.RelEpsilon*math.Abs(a)
+}
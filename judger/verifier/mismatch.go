@@ -0,0 +1,79 @@
+package verifier
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+const (
+	snippetSize = 64  // Expected、Actual 的最大长度
+	previewSize = 256 // Preview 的最大长度
+)
+
+// 输出与答案第一个不同的位置，作为WA的错误返回，用于前端展示 "expected 12, got 13"
+type Mismatch struct {
+	Case     int    // 第几个测试用例，从0开始，由executor设置
+	Line     int    // 输出中的行，从1开始
+	Column   int    // 输出中的列，从1开始，按字节计算
+	Expected string // 答案在该位置的内容
+	Actual   string // 输出在该位置的内容
+	Preview  string // 输出文件的开头部分
+}
+
+func (m *Mismatch) Error() string {
+	return fmt.Sprintf("wrong answer at line %v column %v: expected %q, got %q", m.Line, m.Column, m.Expected, m.Actual)
+}
+
+func newMismatch(line, column int, expected, actual string) *Mismatch {
+	return &Mismatch{
+		Line:     line,
+		Column:   column,
+		Expected: snippet(expected, column),
+		Actual:   snippet(actual, column),
+	}
+}
+
+// 截取s中第column列附近的内容，两端对齐到UTF-8字符的边界，不截断多字节字符
+func snippet(s string, column int) string {
+	if len(s) <= snippetSize {
+		return s
+	}
+	start := column - 1 - snippetSize/2
+	if start < 0 {
+		start = 0
+	}
+	if start+snippetSize > len(s) {
+		start = len(s) - snippetSize
+	}
+	end := start + snippetSize
+	for start < end && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	for end > start && end < len(s) && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[start:end]
+}
+
+// 读取文件的开头部分
+func readPreview(fileName string) string {
+	fd, err := os.Open(fileName)
+	if err != nil {
+		return ""
+	}
+	defer fd.Close()
+
+	buf := make([]byte, previewSize)
+	n, _ := io.ReadFull(fd, buf)
+	return string(buf[:n])
+}
+
+// 为Mismatch补充输出文件的预览
+func withPreview(err error, outputFileName string) error {
+	if m, ok := err.(*Mismatch); ok {
+		m.Preview = readPreview(outputFileName)
+	}
+	return err
+}
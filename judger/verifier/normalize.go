@@ -0,0 +1,191 @@
+package verifier
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// 检测编码时读取的文件开头的大小
+const detectSize = 64 << 10
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// 校验前对输出和答案进行的规范化处理，零值表示不做任何处理
+type Normalization struct {
+	LineEndings  bool // CRLF、CR 转换为 LF
+	StripBOM     bool // 去掉文件开头的UTF-8 BOM
+	TrimTrailing bool // 去掉行尾的空格、制表符 和 文件末尾的空行，最后一行补齐换行
+	DetectGBK    bool // 文件开头不是合法的UTF-8时，按GBK解码为UTF-8
+}
+
+// 默认对Windows上传的答案文件做兼容处理
+var DefaultNormalization = Normalization{
+	LineEndings:  true,
+	StripBOM:     true,
+	TrimTrailing: true,
+	DetectGBK:    true,
+}
+
+func (n Normalization) enabled() bool {
+	return n != Normalization{}
+}
+
+// 返回规范化后的Reader
+func (n Normalization) Reader(r io.Reader) io.Reader {
+	if !n.enabled() {
+		return r
+	}
+
+	br := bufio.NewReaderSize(r, detectSize)
+	if n.StripBOM {
+		if b, _ := br.Peek(len(utf8BOM)); bytes.Equal(b, utf8BOM) {
+			br.Discard(len(utf8BOM))
+		}
+	}
+
+	var src io.Reader = br
+	if n.DetectGBK {
+		b, _ := br.Peek(detectSize)
+		if !validUTF8Prefix(b, len(b) == detectSize) {
+			src = transform.NewReader(br, simplifiedchinese.GBK.NewDecoder())
+		}
+	}
+
+	if n.LineEndings || n.TrimTrailing {
+		src = newLineNormalizer(src, n)
+	}
+	return src
+}
+
+// 判断b是否为合法的UTF-8，truncated 为true时b只是文件的开头，忽略末尾被截断的字符
+func validUTF8Prefix(b []byte, truncated bool) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			return truncated && len(b) < utf8.UTFMax && !utf8.FullRune(b)
+		}
+		b = b[size:]
+	}
+	return true
+}
+
+// 逐行处理换行符和行尾空白
+type lineNormalizer struct {
+	scanner      *bufio.Scanner
+	n            Normalization
+	buf          []byte
+	blankLines   int  // 还未输出的空行，TrimTrailing 时文件末尾的空行不输出
+	unterminated bool // 最后一行没有换行符
+}
+
+func newLineNormalizer(r io.Reader, n Normalization) *lineNormalizer {
+	l := &lineNormalizer{n: n}
+	l.scanner = bufio.NewScanner(r)
+	l.scanner.Buffer(make([]byte, 64<<10), maxTokenSize)
+	l.scanner.Split(l.scanLines)
+	return l
+}
+
+// 以 \n、\r\n、\r 作为换行符，LineEndings 为false时只以 \n 作为换行符，\r 保留在行内
+func (l *lineNormalizer) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	for i, b := range data {
+		switch {
+		case b == '\n':
+			if !l.n.LineEndings {
+				return i + 1, data[:i], nil
+			}
+			return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+		case b == '\r' && l.n.LineEndings:
+			if i+1 < len(data) {
+				if data[i+1] == '\n' {
+					return i + 2, data[:i], nil
+				}
+				return i + 1, data[:i], nil
+			}
+			if atEOF {
+				return i + 1, data[:i], nil
+			}
+			// 需要更多数据判断是否为 \r\n
+			return 0, nil, nil
+		}
+	}
+
+	if atEOF && len(data) > 0 {
+		l.unterminated = true
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func (l *lineNormalizer) Read(p []byte) (int, error) {
+	for len(l.buf) == 0 {
+		if !l.scanner.Scan() {
+			if err := l.scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+
+		line := l.scanner.Bytes()
+		if l.n.TrimTrailing {
+			line = bytes.TrimRight(line, " \t")
+			if len(line) == 0 {
+				l.blankLines++
+				continue
+			}
+			l.buf = append(l.buf, bytes.Repeat([]byte{'\n'}, l.blankLines)...)
+			l.blankLines = 0
+			l.buf = append(append(l.buf, line...), '\n')
+			continue
+		}
+
+		l.buf = append(l.buf, line...)
+		if !l.unterminated {
+			l.buf = append(l.buf, '\n')
+		}
+	}
+
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
+
+// 打开文件，返回规范化后的Reader
+func openNormalized(fileName string, n Normalization) (io.Reader, io.Closer, error) {
+	fd, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return n.Reader(fd), fd, nil
+}
+
+// 把规范化后的内容写入同一目录下的临时文件，用于需要文件路径的checker
+// 返回临时文件的路径 及 删除临时文件的函数
+func normalizedCopy(fileName string, n Normalization) (string, func(), error) {
+	src, closer, err := openNormalized(fileName, n)
+	if err != nil {
+		return "", nil, err
+	}
+	defer closer.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), ".normalized-*")
+	if err != nil {
+		return "", nil, err
+	}
+	defer tmp.Close()
+
+	remove := func() { os.Remove(tmp.Name()) }
+	if _, err = io.Copy(tmp, src); err != nil {
+		remove()
+		return "", nil, err
+	}
+	return tmp.Name(), remove, nil
+}
@@ -0,0 +1,82 @@
+package verifier
+
+import (
+	"fmt"
+	"sync"
+)
+
+// 未指定校验器时使用standard
+const Default = "standard"
+
+// 创建校验器的参数，来自题目(model.Question)的配置，各校验器只使用需要的字段
+type Options struct {
+	AbsEpsilon  float64 // float: 绝对误差
+	RelEpsilon  float64 // float: 相对误差
+	Checker     string  // checker: checker 可执行文件，相对checker目录的路径
+	GroupPrefix string  // unordered: 分组标签的前缀
+	Strict      bool    // 不做规范化处理，否则使用 DefaultNormalization
+}
+
+func (o Options) normalization() Normalization {
+	if o.Strict {
+		return Normalization{}
+	}
+	return DefaultNormalization
+}
+
+// 根据参数创建校验器
+type Factory func(opts Options) (Verifier, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// 注册校验器，同名校验器会被覆盖
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = f
+}
+
+// 按名称创建校验器，name为空时使用Default
+func New(name string, opts Options) (Verifier, error) {
+	if name == "" {
+		name = Default
+	}
+
+	mu.RLock()
+	f, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported verifier: %v", name)
+	}
+	return f(opts)
+}
+
+func init() {
+	Register("standard", func(opts Options) (Verifier, error) {
+		return StandardVerifier{Normalize: opts.normalization()}, nil
+	})
+	Register("token", func(opts Options) (Verifier, error) {
+		return TokenVerifier{Normalize: opts.normalization()}, nil
+	})
+	Register("float", func(opts Options) (Verifier, error) {
+		f := NewFloatVerifier(opts.AbsEpsilon, opts.RelEpsilon)
+		f.Normalize = opts.normalization()
+		return f, nil
+	})
+	Register("unordered", func(opts Options) (Verifier, error) {
+		return UnorderedVerifier{GroupPrefix: opts.GroupPrefix, Normalize: opts.normalization()}, nil
+	})
+	Register("unordered-token", func(opts Options) (Verifier, error) {
+		return UnorderedVerifier{ByToken: true, GroupPrefix: opts.GroupPrefix, Normalize: opts.normalization()}, nil
+	})
+	// Sandbox 由executor设置
+	Register("checker", func(opts Options) (Verifier, error) {
+		if opts.Checker == "" {
+			return nil, fmt.Errorf("checker verifier requires a checker")
+		}
+		return CheckerVerifier{Checker: opts.Checker, Normalize: opts.normalization()}, nil
+	})
+}
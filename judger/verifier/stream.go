@@ -0,0 +1,48 @@
+package verifier
+
+import (
+	"fmt"
+	"io"
+	"tgoj/judger/errors"
+)
+
+// 支持流式校验的校验器，程序的输出不写入文件，由executor从容器的stdout直接传入并逐步比较
+// 返回错误后executor会立即结束程序，因此应在出现第一个不同时尽早返回，不必读完output
+type StreamVerifier interface {
+	Verifier
+	VerifyStream(output io.Reader, answerFileName string) (int, error)
+}
+
+// 打开答案文件，规范化后调用verify比较，WA时补充输出开头的预览
+func verifyStream(output io.Reader, answerFileName string, n Normalization, verify func(output, answer io.Reader) (int, error)) (int, error) {
+	answer, closer, err := openNormalized(answerFileName, n)
+	if err != nil {
+		return 0, errors.New(errors.AnswerNotFound, fmt.Sprintf("%v not found", answerFileName))
+	}
+	defer closer.Close()
+
+	// 不检测输出的编码：检测需要先读取输出开头的64KB，发现第一个不同时无法立即返回
+	outputNorm := n
+	outputNorm.DetectGBK = false
+	preview := &previewBuffer{}
+	passed, err := verify(outputNorm.Reader(io.TeeReader(output, preview)), answer)
+	if m, ok := err.(*Mismatch); ok {
+		m.Preview = string(preview.buf)
+	}
+	return passed, err
+}
+
+// 只保留写入内容的开头部分
+type previewBuffer struct {
+	buf []byte
+}
+
+func (p *previewBuffer) Write(b []byte) (int, error) {
+	if n := previewSize - len(p.buf); n > 0 {
+		if n > len(b) {
+			n = len(b)
+		}
+		p.buf = append(p.buf, b[:n]...)
+	}
+	return len(b), nil
+}
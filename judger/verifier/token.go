@@ -0,0 +1,119 @@
+package verifier
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"tgoj/judger/errors"
+)
+
+// 单个token(或一行)的最大长度
+const maxTokenSize = 16 << 20
+
+// 读取输出出错时的错误：输出的一行或一个token超过 maxTokenSize 时，
+// 答案中对应的内容已读取成功、不超过该长度，两者一定不同，判定为WA
+func outputFileError(err error) error {
+	if err == bufio.ErrTooLong {
+		return errors.New(errors.WA, fmt.Sprintf("output line or token exceeds %v bytes", maxTokenSize))
+	}
+	return fmt.Errorf("output file error: %v", err)
+}
+
+// 忽略空白字符的差异，逐个比较以空白分隔的token，返回相同的token数量
+// 行尾空格、多余的空行、文件末尾是否有换行都不影响结果
+type TokenVerifier struct {
+	Normalize Normalization // 比较前的规范化处理
+}
+
+func (t TokenVerifier) Verify(outputFileName, answerFileName string) (int, error) {
+	return verifyTokenFiles(outputFileName, answerFileName, t.Normalize, exactEqual)
+}
+
+func (t TokenVerifier) VerifyStream(output io.Reader, answerFileName string) (int, error) {
+	return verifyStream(output, answerFileName, t.Normalize, func(output, answer io.Reader) (int, error) {
+		return verifyTokens(output, answer, exactEqual)
+	})
+}
+
+func exactEqual(output, answer string) bool {
+	return output == answer
+}
+
+// 打开输出和答案文件，按token比较
+func verifyTokenFiles(outputFileName, answerFileName string, n Normalization, equal func(output, answer string) bool) (int, error) {
+	output, answer, closeFn, err := openFiles(outputFileName, answerFileName, n)
+	if err != nil {
+		return 0, err
+	}
+	defer closeFn()
+
+	tokens, err := verifyTokens(output, answer, equal)
+	return tokens, withPreview(err, outputFileName)
+}
+
+func verifyTokens(output, answer io.Reader, equal func(output, answer string) bool) (tokens int, err error) {
+	outputScanner, answerScanner := newTokenScanner(output), newTokenScanner(answer)
+	for {
+		hasAnswer, hasOutput := answerScanner.Scan(), outputScanner.Scan()
+		if err = answerScanner.Err(); err != nil {
+			return tokens, fmt.Errorf("answer file error: %v", err)
+		}
+		if err = outputScanner.Err(); err != nil {
+			return tokens, outputFileError(err)
+		}
+
+		// 输出结束时，位置为输出文件的末尾
+		switch {
+		case !hasAnswer && !hasOutput:
+			return tokens, nil
+		case !hasAnswer:
+			return tokens, newMismatch(outputScanner.tokenLine, outputScanner.tokenColumn, "", outputScanner.Text())
+		case !hasOutput:
+			return tokens, newMismatch(outputScanner.line, outputScanner.column, answerScanner.Text(), "")
+		}
+
+		if !equal(outputScanner.Text(), answerScanner.Text()) {
+			return tokens, newMismatch(outputScanner.tokenLine, outputScanner.tokenColumn, answerScanner.Text(), outputScanner.Text())
+		}
+		tokens++
+	}
+}
+
+// 按空白分隔读取token，并记录token在文件中的位置
+type tokenScanner struct {
+	*bufio.Scanner
+	line, column           int // 下一个未读取的字节的位置
+	tokenLine, tokenColumn int // 当前token的位置
+}
+
+func newTokenScanner(r io.Reader) *tokenScanner {
+	s := &tokenScanner{line: 1, column: 1}
+	s.Scanner = bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64<<10), maxTokenSize)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanWords(data, atEOF)
+		if token == nil {
+			s.move(data[:advance])
+			return advance, token, err
+		}
+
+		// token 是 data 的子切片
+		start := cap(data) - cap(token)
+		s.move(data[:start])
+		s.tokenLine, s.tokenColumn = s.line, s.column
+		s.move(data[start:advance])
+		return advance, token, err
+	})
+	return s
+}
+
+func (s *tokenScanner) move(data []byte) {
+	for _, b := range data {
+		if b == '\n' {
+			s.line++
+			s.column = 1
+		} else {
+			s.column++
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package verifier
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"tgoj/judger/errors"
+)
+
+// 报告缺少、多余的元素时最多列出的数量
+const maxReportItems = 5
+
+// 把输出和答案视为多重集合比较，适用于"以任意顺序输出所有解"的题目
+// 设置 GroupPrefix 后，以该前缀开头的行作为分组标签(例如 "Case #")，标签的顺序必须相同，每组内部单独比较
+type UnorderedVerifier struct {
+	ByToken     bool          // 以空白分隔的token为元素，否则以行为元素(忽略行尾空白和空行)
+	GroupPrefix string        // 分组标签的前缀，为空时不分组
+	Normalize   Normalization // 比较前的规范化处理
+}
+
+// 一组元素
+type itemGroup struct {
+	label string
+	items []string
+}
+
+func (u UnorderedVerifier) Verify(outputFileName, answerFileName string) (int, error) {
+	output, answer, closeFn, err := openFiles(outputFileName, answerFileName, u.Normalize)
+	if err != nil {
+		return 0, err
+	}
+	defer closeFn()
+
+	return u.verify(output, answer)
+}
+
+// 返回相同的元素数量
+func (u UnorderedVerifier) verify(output, answer io.Reader) (int, error) {
+	outputGroups, err := u.readGroups(output)
+	if err != nil {
+		return 0, outputFileError(err)
+	}
+	answerGroups, err := u.readGroups(answer)
+	if err != nil {
+		return 0, fmt.Errorf("answer file error: %v", err)
+	}
+
+	var matched int
+	for i, answerGroup := range answerGroups {
+		if i >= len(outputGroups) {
+			return matched, errors.New(errors.WA, fmt.Sprintf("group %q not found in output", answerGroup.label))
+		}
+		outputGroup := outputGroups[i]
+		if outputGroup.label != answerGroup.label {
+			return matched, errors.New(errors.WA,
+				fmt.Sprintf("wrong group label: expected %q, got %q", answerGroup.label, outputGroup.label))
+		}
+
+		missing, extra, n := diffItems(outputGroup.items, answerGroup.items)
+		matched += n
+		if len(missing) > 0 || len(extra) > 0 {
+			msg := fmt.Sprintf("missing %v item(s) %v, extra %v item(s) %v",
+				len(missing), firstItems(missing), len(extra), firstItems(extra))
+			if answerGroup.label != "" {
+				msg = fmt.Sprintf("group %q: %v", answerGroup.label, msg)
+			}
+			return matched, errors.New(errors.WA, msg)
+		}
+	}
+
+	if len(outputGroups) > len(answerGroups) {
+		return matched, errors.New(errors.WA,
+			fmt.Sprintf("extra group %q in output", outputGroups[len(answerGroups)].label))
+	}
+	return matched, nil
+}
+
+// 读取所有元素，第一个标签之前的元素属于标签为空的组
+func (u UnorderedVerifier) readGroups(r io.Reader) ([]itemGroup, error) {
+	groups := []itemGroup{{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxTokenSize)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if u.GroupPrefix != "" && strings.HasPrefix(line, u.GroupPrefix) {
+			groups = append(groups, itemGroup{label: line})
+			continue
+		}
+
+		last := &groups[len(groups)-1]
+		if u.ByToken {
+			last.items = append(last.items, strings.Fields(line)...)
+		} else if line != "" {
+			last.items = append(last.items, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// 第一个标签之前没有元素时去掉该组
+	if len(groups) > 1 && len(groups[0].items) == 0 {
+		groups = groups[1:]
+	}
+	return groups, nil
+}
+
+// 按多重集合比较，返回答案中有而输出中没有的元素、输出中多余的元素 及 相同的元素数量
+func diffItems(output, answer []string) (missing, extra []string, matched int) {
+	counts := make(map[string]int, len(answer))
+	for _, item := range answer {
+		counts[item]++
+	}
+	for _, item := range output {
+		if counts[item] > 0 {
+			counts[item]--
+			matched++
+		} else {
+			extra = append(extra, item)
+		}
+	}
+	for item, n := range counts {
+		for ; n > 0; n-- {
+			missing = append(missing, item)
+		}
+	}
+	sort.Strings(missing)
+	return
+}
+
+func firstItems(items []string) []string {
+	if len(items) > maxReportItems {
+		return items[:maxReportItems]
+	}
+	return items
+}
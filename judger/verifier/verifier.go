@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"tgoj/judger/errors"
 )
@@ -15,42 +14,98 @@ type Verifier interface {
 	Verify(outputFileName, answerFileName string) (int, error)
 }
 
-// 适用于 输出 和 答案 按行存储每一个case的场景
-type StandardVerifier struct{}
+// 校验结果对应的判定，输出与答案不同为WA，部分正确为PC
+func Verdict(err error) errors.JudgerError {
+	switch err.(type) {
+	case *Mismatch:
+		return errors.WA
+	case PartialError:
+		return errors.PC
+	}
+	return errors.Code(err)
+}
+
+// 校验结果对应的得分比例：通过为1，部分正确为 PartialError.Score，其他为0
+func Score(err error) float64 {
+	if err == nil {
+		return 1
+	}
+	if e, ok := err.(PartialError); ok {
+		return e.Score
+	}
+	return 0
+}
+
+// 打开输出和答案文件，返回规范化后的Reader 及 关闭文件的函数
+func openFiles(outputFileName, answerFileName string, n Normalization) (output, answer io.Reader, closeFn func(), err error) {
+	output, outputCloser, err := openNormalized(outputFileName, n)
+	if err != nil {
+		return nil, nil, nil, errors.New(errors.OutputNotFound, fmt.Sprintf("%v not found", outputFileName))
+	}
 
-func (StandardVerifier) Verify(outputFileName, answerFileName string) (cases int, err error) {
-	outputFd, err := os.Open(outputFileName)
+	answer, answerCloser, err := openNormalized(answerFileName, n)
 	if err != nil {
-		return 0, errors.New(errors.OutputNotFound, fmt.Sprintf("%v not found", outputFileName))
+		outputCloser.Close()
+		return nil, nil, nil, errors.New(errors.AnswerNotFound, fmt.Sprintf("%v not found", answerFileName))
 	}
-	defer outputFd.Close()
-	outputReader := bufio.NewReader(outputFd)
 
-	answerFd, err := os.Open(answerFileName)
+	return output, answer, func() {
+		outputCloser.Close()
+		answerCloser.Close()
+	}, nil
+}
+
+// 适用于 输出 和 答案 按行存储每一个case的场景
+type StandardVerifier struct {
+	Normalize Normalization // 比较前的规范化处理
+}
+
+func (s StandardVerifier) Verify(outputFileName, answerFileName string) (int, error) {
+	output, answer, closeFn, err := openFiles(outputFileName, answerFileName, s.Normalize)
 	if err != nil {
-		return 0, errors.New(errors.AnswerNotFound, fmt.Sprintf("%v not found", answerFileName))
+		return 0, err
 	}
-	defer answerFd.Close()
-	answerReader := bufio.NewReader(answerFd)
+	defer closeFn()
+
+	cases, err := verifyLines(output, answer)
+	return cases, withPreview(err, outputFileName)
+}
+
+func (s StandardVerifier) VerifyStream(output io.Reader, answerFileName string) (int, error) {
+	return verifyStream(output, answerFileName, s.Normalize, verifyLines)
+}
 
+// 逐行比较，返回相同的行数
+func verifyLines(output, answer io.Reader) (cases int, err error) {
+	outputReader, answerReader := bufio.NewReader(output), bufio.NewReader(answer)
 	for {
 		// 逐行读取，一行即一个case
 		answer, err := answerReader.ReadString('\n')
 		output, anotherErr := outputReader.ReadString('\n')
-		if err != nil || anotherErr != nil {
-			if err == io.EOF && anotherErr == io.EOF {
-				return cases, nil
-			}
-			if err != nil {
-				return cases, fmt.Errorf("answer file error: %v", err)
-			}
-			return cases, fmt.Errorf("output file error: %v", anotherErr)
+		if err != nil && err != io.EOF {
+			return cases, fmt.Errorf("answer file error: %v", err)
+		}
+		if anotherErr != nil && anotherErr != io.EOF {
+			return cases, outputFileError(anotherErr)
 		}
 
 		if strings.Compare(answer, output) != 0 {
-			return cases, fmt.Errorf("wrong answer at %v case", cases)
+			return cases, newMismatch(cases+1, firstDiff(answer, output)+1, answer, output)
 		}
 
+		// 内容相同时，两个文件同时结束
+		if err == io.EOF {
+			return cases, nil
+		}
 		cases++
 	}
 }
+
+// 第一个不同字节的下标
+func firstDiff(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,249 @@
+package verifier
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"tgoj/judger/errors"
+	"time"
+	"unicode/utf8"
+)
+
+func TestVerifyTokens(t *testing.T) {
+	var tests = []struct {
+		output  string
+		answer  string
+		tokens  int
+		verdict errors.JudgerError
+	}{
+		{"2\n3\n4\n", "2\n3\n4\n", 3, errors.AC},
+		{"2 3  4", "2\n3\n4\n", 3, errors.AC},
+		{"2\r\n3 \n4\n\n\n", "2\n3\n4", 3, errors.AC},
+		{"2\n3\n5\n", "2\n3\n4\n", 2, errors.WA},
+		{"2\n3\n", "2\n3\n4\n", 2, errors.WA},
+		{"2\n3\n4\n5", "2\n3\n4\n", 3, errors.WA},
+	}
+
+	for _, test := range tests {
+		tokens, err := verifyTokens(strings.NewReader(test.output), strings.NewReader(test.answer),
+			func(output, answer string) bool { return output == answer })
+		if tokens != test.tokens || Verdict(err) != test.verdict {
+			t.Errorf("verifyTokens(%q, %q) = %v, %v", test.output, test.answer, tokens, err)
+		}
+	}
+}
+
+func TestFloatVerifier(t *testing.T) {
+	var tests = []struct {
+		verifier FloatVerifier
+		output   string
+		answer   string
+		equal    bool
+	}{
+		{NewFloatVerifier(0, 0), "0.333333333", "0.3333333334", true},
+		{NewFloatVerifier(0, 0), "0.3334", "0.3333333334", false},
+		{FloatVerifier{AbsEpsilon: 1e-3}, "0.3334", "0.3333333334", true},
+		{FloatVerifier{RelEpsilon: 1e-6}, "1000000.5", "1000000", true},
+		{FloatVerifier{RelEpsilon: 1e-6}, "1.5", "1", false},
+		{NewFloatVerifier(0, 0), "YES", "YES", true},
+		{NewFloatVerifier(0, 0), "yes", "YES", false},
+		{NewFloatVerifier(0, 0), "1e-7", "0", true},
+		{NewFloatVerifier(0, 0), "nan", "0", false},
+	}
+
+	for _, test := range tests {
+		if equal := test.verifier.equal(test.output, test.answer); equal != test.equal {
+			t.Errorf("%+v.equal(%q, %q) = %v", test.verifier, test.output, test.answer, equal)
+		}
+	}
+}
+
+func TestUnorderedVerifier(t *testing.T) {
+	var tests = []struct {
+		verifier UnorderedVerifier
+		output   string
+		answer   string
+		matched  int
+		verdict  errors.JudgerError
+	}{
+		{UnorderedVerifier{}, "1 2\n3 4\n", "3 4\n1 2\n", 2, errors.AC},
+		{UnorderedVerifier{}, "1 2\n1 2\n", "1 2\n3 4\n", 1, errors.WA},
+		{UnorderedVerifier{ByToken: true}, "4 3\n2 1", "1 2 3 4\n", 4, errors.AC},
+		{UnorderedVerifier{GroupPrefix: "Case #"}, "Case #1:\nb\na\nCase #2:\nc\n", "Case #1:\na\nb\nCase #2:\nc\n", 3, errors.AC},
+		{UnorderedVerifier{GroupPrefix: "Case #"}, "Case #1:\nc\nCase #2:\na\nb\n", "Case #1:\na\nb\nCase #2:\nc\n", 0, errors.WA},
+		{UnorderedVerifier{GroupPrefix: "Case #"}, "Case #1:\na\n", "Case #1:\na\nCase #2:\nc\n", 1, errors.WA},
+	}
+
+	for _, test := range tests {
+		matched, err := test.verifier.verify(strings.NewReader(test.output), strings.NewReader(test.answer))
+		if matched != test.matched || Verdict(err) != test.verdict {
+			t.Errorf("%+v.verify(%q, %q) = %v, %v", test.verifier, test.output, test.answer, matched, err)
+		}
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	var tests = []struct {
+		output   string
+		answer   string
+		mismatch Mismatch
+	}{
+		{"12\n13\n", "12\n12\n", Mismatch{Line: 2, Column: 1, Expected: "12", Actual: "13"}},
+		{"12 13\n  14\n", "12 13 15\n", Mismatch{Line: 2, Column: 3, Expected: "15", Actual: "14"}},
+		{"12\n", "12 13\n", Mismatch{Line: 2, Column: 1, Expected: "13", Actual: ""}},
+		{"12\n\n 13", "12", Mismatch{Line: 3, Column: 2, Expected: "", Actual: "13"}},
+	}
+
+	for _, test := range tests {
+		_, err := verifyTokens(strings.NewReader(test.output), strings.NewReader(test.answer),
+			func(output, answer string) bool { return output == answer })
+		m, ok := err.(*Mismatch)
+		if !ok || *m != test.mismatch {
+			t.Errorf("verifyTokens(%q, %q) = %v", test.output, test.answer, err)
+		}
+	}
+
+	if s := snippet(strings.Repeat("a", 100)+"b"+strings.Repeat("a", 100), 101); len(s) != snippetSize || s[snippetSize/2] != 'b' {
+		t.Errorf("snippet = %q", s)
+	}
+	// 多字节字符不被截断
+	for column := 1; column <= 150; column++ {
+		if s := snippet(strings.Repeat("中", 50), column); !utf8.ValidString(s) || len(s) > snippetSize || len(s) < snippetSize-2*(utf8.UTFMax-1) {
+			t.Errorf("snippet(column %v) = %q", column, s)
+		}
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	var tests = []struct {
+		n      Normalization
+		input  string
+		output string
+	}{
+		{Normalization{}, "1\r\n2 \r\n", "1\r\n2 \r\n"},
+		{Normalization{LineEndings: true}, "1\r\n2 \r3", "1\n2 \n3"},
+		{Normalization{LineEndings: true}, "1\r", "1\n"},
+		{Normalization{StripBOM: true}, "\xEF\xBB\xBF1\n", "1\n"},
+		{Normalization{TrimTrailing: true}, "1 \t\n\n2\n\n \n", "1\n\n2\n"},
+		{Normalization{TrimTrailing: true}, "1\r\n2", "1\r\n2\n"},
+		{Normalization{DetectGBK: true}, "\xc4\xe3\xba\xc3\n", "你好\n"},
+		{Normalization{DetectGBK: true}, "你好\n", "你好\n"},
+		{DefaultNormalization, "\xEF\xBB\xBF1 \r\n2\r\n\r\n", "1\n2\n"},
+	}
+
+	for _, test := range tests {
+		b, err := ioutil.ReadAll(test.n.Reader(strings.NewReader(test.input)))
+		if err != nil || string(b) != test.output {
+			t.Errorf("%+v.Reader(%q) = %q, %v", test.n, test.input, b, err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	var tests = []struct {
+		name     string
+		opts     Options
+		verifier Verifier
+		ok       bool
+	}{
+		{"", Options{}, StandardVerifier{Normalize: DefaultNormalization}, true},
+		{"token", Options{Strict: true}, TokenVerifier{}, true},
+		{"float", Options{AbsEpsilon: 1e-3}, FloatVerifier{AbsEpsilon: 1e-3, Normalize: DefaultNormalization}, true},
+		{"unordered-token", Options{GroupPrefix: "Case #"},
+			UnorderedVerifier{ByToken: true, GroupPrefix: "Case #", Normalize: DefaultNormalization}, true},
+		{"checker", Options{Checker: "1/checker"}, CheckerVerifier{Checker: "1/checker", Normalize: DefaultNormalization}, true},
+		{"checker", Options{}, nil, false},
+		{"unknown", Options{}, nil, false},
+	}
+
+	for _, test := range tests {
+		v, err := New(test.name, test.opts)
+		if (err == nil) != test.ok || v != test.verifier {
+			t.Errorf("New(%q, %+v) = %+v, %v", test.name, test.opts, v, err)
+		}
+	}
+}
+
+func TestVerifyStream(t *testing.T) {
+	answer, err := ioutil.TempFile("", "answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(answer.Name())
+	answer.WriteString("1\r\n2\r\n3\r\n")
+	answer.Close()
+
+	var tests = []struct {
+		verifier StreamVerifier
+		output   string
+		passed   int
+		verdict  errors.JudgerError
+	}{
+		{StandardVerifier{Normalize: DefaultNormalization}, "1\n2\n3\n", 3, errors.AC},
+		{StandardVerifier{}, "1\n2\n3\n", 0, errors.WA},
+		{TokenVerifier{}, "1 2 3", 3, errors.AC},
+		{NewFloatVerifier(0, 0), "1 2.0000001 4", 2, errors.WA},
+	}
+
+	for _, test := range tests {
+		passed, err := test.verifier.VerifyStream(strings.NewReader(test.output), answer.Name())
+		if passed != test.passed || Verdict(err) != test.verdict {
+			t.Errorf("%+v.VerifyStream(%q) = %v, %v", test.verifier, test.output, passed, err)
+		}
+		if m, ok := err.(*Mismatch); ok && m.Preview != test.output {
+			t.Errorf("preview = %q, want %q", m.Preview, test.output)
+		}
+	}
+}
+
+// 流式校验在输出未结束时发现第一个不同就返回，默认规范化的GBK检测不等待读取64KB
+func TestVerifyStream_FirstMismatch(t *testing.T) {
+	answer, err := ioutil.TempFile("", "answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(answer.Name())
+	answer.WriteString("1\n2\n3\n")
+	answer.Close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte("1\n0\n"))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := StandardVerifier{Normalize: DefaultNormalization}.VerifyStream(pr, answer.Name())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if Verdict(err) != errors.WA {
+			t.Errorf("VerifyStream() = %v, want WA", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("VerifyStream did not return before the output ended")
+	}
+}
+
+// 输出的一行或一个token超过最大长度时判定为WA，而不是读取错误
+func TestLongOutput(t *testing.T) {
+	answer, err := ioutil.TempFile("", "answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(answer.Name())
+	answer.WriteString("1\n")
+	answer.Close()
+	output := strings.Repeat("a", maxTokenSize+1)
+
+	for _, v := range []StreamVerifier{StandardVerifier{Normalize: DefaultNormalization}, TokenVerifier{}, NewFloatVerifier(0, 0)} {
+		if _, err := v.VerifyStream(strings.NewReader(output), answer.Name()); Verdict(err) != errors.WA {
+			t.Errorf("%T.VerifyStream() = %v, want WA", v, err)
+		}
+	}
+	if _, err := (UnorderedVerifier{}).verify(strings.NewReader(output), strings.NewReader("1\n")); Verdict(err) != errors.WA {
+		t.Errorf("UnorderedVerifier.verify() = %v, want WA", err)
+	}
+}
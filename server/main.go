@@ -6,6 +6,7 @@ import (
 	"os"
 	"tgoj/server/global"
 	"tgoj/server/model"
+	"tgoj/server/service"
 )
 
 func main() {
@@ -44,6 +45,7 @@ func main() {
 		TimeLimit: 1.0,
 	}
 
-	result := global.DB.Create(&q1)
-	fmt.Println(*result)
+	// 设置了Validator的题目需要提供 Publisher.Validator(例如DockerExecutor)，输入数据通过检查后才会发布
+	invalid, err := service.Publisher{DB: global.DB, Resource: os.Getenv("Resource")}.Publish(&q1)
+	fmt.Println(invalid, err)
 }
@@ -4,13 +4,20 @@ import "gorm.io/gorm"
 
 type Question struct {
 	gorm.Model
-	Title       string  `json:"title" gorm:"type:varchar(100);unique;comment:题目名称"`
-	Description string  `json:"description"  gorm:"comment:题目描述"`
-	Level       string  `json:"level"  gorm:"type:varchar(10);comment:难度等级"`
-	TestData    string  `json:"test_data"  gorm:"comment:评测输入的数据"`
-	TestAnswer  string  `json:"test_answer"  gorm:"comment:评测的正确结果"`
-	Example     string  `json:"example"  gorm:"comment:示例"`
-	Tags        string  `json:"tags"  gorm:"comment:标签"`
-	MemoryLimit int64   `json:"memory_limit"  gorm:"comment:内存限制"`
-	TimeLimit   float64 `json:"time_limit"  gorm:"comment:时间限制"`
+	Title         string  `json:"title" gorm:"type:varchar(100);unique;comment:题目名称"`
+	Description   string  `json:"description"  gorm:"comment:题目描述"`
+	Level         string  `json:"level"  gorm:"type:varchar(10);comment:难度等级"`
+	TestData      string  `json:"test_data"  gorm:"comment:评测输入的数据"`
+	TestAnswer    string  `json:"test_answer"  gorm:"comment:评测的正确结果"`
+	Example       string  `json:"example"  gorm:"comment:示例"`
+	Tags          string  `json:"tags"  gorm:"comment:标签"`
+	MemoryLimit   int64   `json:"memory_limit"  gorm:"comment:内存限制"`
+	TimeLimit     float64 `json:"time_limit"  gorm:"comment:时间限制"`
+	AbsEpsilon    float64 `json:"abs_epsilon"  gorm:"comment:浮点数答案允许的绝对误差"`
+	RelEpsilon    float64 `json:"rel_epsilon"  gorm:"comment:浮点数答案允许的相对误差"`
+	StrictCompare bool    `json:"strict_compare"  gorm:"comment:校验前不规范化换行、BOM、行尾空白和编码"`
+	Verifier      string  `json:"verifier"  gorm:"type:varchar(20);default:standard;comment:校验器名称(standard、token、float、unordered、unordered-token、checker)"`
+	Checker       string  `json:"checker"  gorm:"comment:special judge的checker，相对checker目录的路径"`
+	GroupPrefix   string  `json:"group_prefix"  gorm:"comment:unordered校验器的分组标签前缀"`
+	Validator     string  `json:"validator"  gorm:"comment:检查输入数据的validator，相对validator目录的路径，发布前对每个输入文件运行"`
 }
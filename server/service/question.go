@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"tgoj/judger"
+	"tgoj/server/model"
+)
+
+// 运行题目的validator检查输入数据，DockerExecutor 实现了该接口
+type InputValidator interface {
+	ValidateInputs(validator string, inputs []string) ([]judger.InvalidInput, error)
+}
+
+// 发布题目: 设置了 Question.Validator 的题目，输入数据全部通过validator后才写入数据库
+type Publisher struct {
+	DB        *gorm.DB
+	Validator InputValidator // 为nil时不能发布设置了Validator的题目
+	Resource  string         // 评测资源目录，输入数据写入 input/question/<ID>.txt
+}
+
+// 返回未通过validator的输入，不为空时题目不会被发布
+func (p Publisher) Publish(q *model.Question) (invalid []judger.InvalidInput, err error) {
+	if q.Validator != "" && p.Validator == nil {
+		return nil, fmt.Errorf("question %q has validator %q, but no validator runner is configured", q.Title, q.Validator)
+	}
+
+	// 在事务中创建，得到ID后写入输入数据并检查，未通过时回滚
+	var input string
+	err = p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(q).Error; err != nil {
+			return err
+		}
+		if q.Validator == "" {
+			return nil
+		}
+
+		input = fmt.Sprintf("question/%d.txt", q.ID)
+		if err := p.writeInput(input, q.TestData); err != nil {
+			return err
+		}
+		var err error
+		if invalid, err = p.Validator.ValidateInputs(q.Validator, []string{input}); err != nil {
+			return err
+		}
+		if len(invalid) > 0 {
+			return invalid[0]
+		}
+		return nil
+	})
+	// 回滚后ID可能被之后创建的题目复用，删除已写入的输入数据
+	if err != nil && input != "" {
+		p.removeInput(input)
+	}
+	return invalid, err
+}
+
+// 把输入数据写入 input 目录下的path
+func (p Publisher) writeInput(path, data string) error {
+	name := filepath.Join(p.Resource, "input", path)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, []byte(data), 0644)
+}
+
+// 删除 input 目录下的path，文件不存在时忽略
+func (p Publisher) removeInput(path string) {
+	if err := os.Remove(filepath.Join(p.Resource, "input", path)); err != nil && !os.IsNotExist(err) {
+		log.Printf("remove input %v: %v", path, err)
+	}
+}